@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
@@ -11,27 +13,165 @@ import (
 	releases "github.com/giantswarm/releases/sdk/api/v1alpha1"
 	"gopkg.in/yaml.v3"
 	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/core/v1beta1"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/expression"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/policy"
 )
 
 const (
 	defaultAddress        = ":8443"
 	defaultMetricsAddress = ":8080"
+
+	defaultWebhookConfigurationName = "app-admission-controller"
+	defaultWebhookServiceName       = "app-admission-controller"
+	defaultWebhookServiceNamespace  = "giantswarm"
+)
+
+var defaultSelfSignedCertHosts = []string{"localhost", "127.0.0.1", "host.docker.internal"}
+
+const (
+	// CommandServe runs the admission webhooks server. It is the default
+	// command.
+	CommandServe = "serve"
+	// CommandBackfill computes, and optionally applies, current mutation
+	// defaults for existing apps.
+	CommandBackfill = "backfill"
+	// CommandCheck runs App CR manifests through the admission logic
+	// against fixture objects, without a cluster.
+	CommandCheck = "check"
+	// CommandReplay replays recorded AdmissionReviews against fixture
+	// objects, without a cluster.
+	CommandReplay = "replay"
+	// CommandManifests renders the webhook configurations, without a
+	// cluster.
+	CommandManifests = "manifests"
 )
 
 type Config struct {
+	Command string
+
 	Address        string
 	CertFile       string
 	KeyFile        string
 	MetricsAddress string
 	Provider       string
 
+	// Configuration for running out of cluster
+	Kubeconfig          string
+	KubeContext         string
+	SelfSignedCert      bool
+	SelfSignedCertHosts []string
+	DevWebhookInstall   bool
+	DevWebhookURL       string
+
+	// Configuration for self-managed TLS
+	SelfManagedTLS                bool
+	SelfManagedTLSHosts           []string
+	SelfManagedTLSSecretName      string
+	SelfManagedTLSSecretNamespace string
+
+	// Configuration for verifying client certificates
+	ClientAllowedSubjects []string
+	ClientCAFile          string
+
+	// Configuration for serving metrics securely
+	MetricsAuthAPIGroup    string
+	MetricsAuthAudiences   []string
+	MetricsAuthName        string
+	MetricsAuthNamespace   string
+	MetricsAuthResource    string
+	MetricsAuthSubresource string
+	MetricsSecure          bool
+
+	// Configuration for generating webhook configurations
+	WebhookCertManagerCertificate string
+	WebhookConfigurationName      string
+	WebhookReconcile              bool
+	WebhookServiceName            string
+	WebhookServiceNamespace       string
+	ManifestsCAFile               string
+	ManifestsURL                  string
+
 	// Configuration for security validation
-	AppBlacklist       []string
-	CatalogBlacklist   []string
-	GroupWhitelist     []string
-	NamespaceBlacklist []string
-	UserWhitelist      []string
+	AppBlacklist          []string
+	CatalogBlacklist      []string
+	GroupWhitelist        []string
+	NamespaceBlacklist    []string
+	ReferenceAccessReview bool
+	UserWhitelist         []string
+
+	// Configuration for checking clusters targeted by apps
+	RestrictClusterTargeting bool
+
+	// Configuration for protecting fields managed by the mutator
+	ProtectedFields []string
+
+	// Configuration for protecting finalizers and labels
+	ProtectedFinalizers []string
+	ProtectedLabels     []string
+
+	// Configuration for guarding deletions of critical apps
+	CriticalAppNames    []string
+	CriticalAppCatalogs []string
+	CriticalAppLabels   []string
+
+	// Configuration for restricting changes of apps to their owner teams
+	OwnerTeams map[string][]string
+
+	// Configuration for per tenant catalog and app policies
+	PolicyFile string
+	Policies   []policy.Policy
+
+	// Configuration for CEL validation rules
+	RuleFile string
+	Rules    []expression.Rule
+
+	// Configuration for enforcement modes of rules
+	RuleModes           map[string]enforcement.Mode
+	EnforcementOverride enforcement.Mode
+
+	// Configuration for break-glass tokens
+	BreakGlassSecretName      string
+	BreakGlassSecretNamespace string
+	BreakGlassRules           []string
+
+	// Configuration for shadow evaluation of a candidate configuration
+	CandidateFile string
+
+	// Configuration for AppAdmissionPolicy objects
+	PolicyObjects           bool
+	PolicyObjectsNamespaced bool
+
+	// Configuration for authorising changes to privileged fields
+	RestrictPrivilegedFields bool
+	PrivilegedGroups         []string
+	PrivilegedUsers          []string
+
+	// Configuration for the audit of existing apps
+	AuditInterval                 time.Duration
+	AuditStatusConfigMapName      string
+	AuditStatusConfigMapNamespace string
+
+	// Configuration for backfilling existing apps
+	BackfillApply     bool
+	BackfillNamespace string
+	BackfillRate      float64
+	BackfillSelector  string
+
+	// Configuration for checking App CR manifests offline
+	CheckFiles    []string
+	CheckFixtures []string
+	CheckGroups   []string
+	CheckUser     string
+
+	// Configuration for recording and replaying AdmissionReviews
+	RecordFile       string
+	RecordSampleRate float64
+	ReplayFile       string
+	ReplayFixtures   []string
 
 	// Configuration for PSP removal
 	PSPConfigFile string
@@ -64,10 +204,120 @@ func Parse() (Config, error) {
 		config.Logger = newLogger
 	}
 
-	// Create a new k8sclient that is used by all admitters.
-	var k8sClient k8sclient.Interface
-	{
-		restConfig, err := restclient.InClusterConfig()
+	kingpin.Flag("provider", "Provider of the management cluster. One of aws, azure, kvm").Required().StringVar(&config.Provider)
+
+	kingpin.Flag("whitelist-group", "Whitelisted group, a matcher rule matching exactly by default").StringsVar(&config.GroupWhitelist)
+	kingpin.Flag("whitelist-user", "Whitelisted user, a matcher rule matching by prefix by default").StringsVar(&config.UserWhitelist)
+	kingpin.Flag("blacklist-app", "Blacklisted apps, a matcher rule matching exactly by default").StringsVar(&config.AppBlacklist)
+	kingpin.Flag("blacklist-catalog", "Blacklisted catalogs, a matcher rule matching exactly by default").StringsVar(&config.CatalogBlacklist)
+	kingpin.Flag("blacklist-namespace", "Blacklisted namespaces, a matcher rule matching exactly, or by prefix or suffix when starting or ending with a hyphen, by default").StringsVar(&config.NamespaceBlacklist)
+	kingpin.Flag("restrict-privileged-fields", "Only allow whitelisted and privileged actors to set or change .spec.kubeConfig.inCluster and the unique version label").BoolVar(&config.RestrictPrivilegedFields)
+	kingpin.Flag("privileged-group", "Group allowed to set or change privileged fields, in addition to whitelisted ones").StringsVar(&config.PrivilegedGroups)
+	kingpin.Flag("privileged-user", "User allowed to set or change privileged fields, in addition to whitelisted ones").StringsVar(&config.PrivilegedUsers)
+	kingpin.Flag("policy-file", "File containing per tenant catalog and app policies").StringVar(&config.PolicyFile)
+	kingpin.Flag("rule-file", "File containing CEL validation rules for App CRs").StringVar(&config.RuleFile)
+	ruleModes := kingpin.Flag("rule-mode", "Enforcement mode of a rule as <rule ID>=<mode>, one of enforce, warn, audit and dry-run").StringMap()
+	enforcementOverride := kingpin.Flag("enforcement-override", "Enforcement mode of all rules, overriding their own, e.g. warn during incidents").Enum("", "enforce", "warn", "audit", "dry-run")
+	kingpin.Flag("psp-config-file", "File containing PSP patch configuration").StringVar(&config.PSPConfigFile)
+	kingpin.Flag("kubeconfig", "Kubeconfig file to connect to the cluster with, the in-cluster configuration is used when empty").StringVar(&config.Kubeconfig)
+	kingpin.Flag("context", "Kubeconfig context to connect to the cluster with").StringVar(&config.KubeContext)
+
+	serve := kingpin.Command(CommandServe, "Run the admission webhooks server").Default()
+	serve.Flag("address", "The address to listen on").Default(defaultAddress).StringVar(&config.Address)
+	serve.Flag("metrics-address", "The metrics address for Prometheus").Default(defaultMetricsAddress).StringVar(&config.MetricsAddress)
+	serve.Flag("tls-cert-file", "File containing the certificate for HTTPS").StringVar(&config.CertFile)
+	serve.Flag("tls-key-file", "File containing the private key for HTTPS").StringVar(&config.KeyFile)
+	serve.Flag("self-signed-cert", "Generate a self-signed certificate for HTTPS at startup instead of reading it from files").BoolVar(&config.SelfSignedCert)
+	serve.Flag("self-signed-cert-host", "DNS name or IP address the self-signed certificate is valid for").Default(defaultSelfSignedCertHosts...).StringsVar(&config.SelfSignedCertHosts)
+	serve.Flag("dev-webhook-url", "Base URL the API server reaches this server at, e.g. https://host.docker.internal:8443; prints matching webhook configurations when set").StringVar(&config.DevWebhookURL)
+	serve.Flag("dev-webhook-install", "Install the webhook configurations pointing at --dev-webhook-url instead of printing them, and remove them on shutdown").BoolVar(&config.DevWebhookInstall)
+	serve.Flag("self-managed-tls", "Generate and rotate the CA and the serving certificate, written to --tls-cert-file and --tls-key-file, instead of relying on cert-manager").BoolVar(&config.SelfManagedTLS)
+	serve.Flag("self-managed-tls-host", "DNS name or IP address the self-managed serving certificate is valid for").StringsVar(&config.SelfManagedTLSHosts)
+	serve.Flag("self-managed-tls-secret-name", "Name of the Secret to store the self-managed CA and serving certificate in").StringVar(&config.SelfManagedTLSSecretName)
+	serve.Flag("self-managed-tls-secret-namespace", "Namespace of the Secret to store the self-managed CA and serving certificate in").StringVar(&config.SelfManagedTLSSecretNamespace)
+	serve.Flag("client-ca-file", "File containing the CA bundle to verify client certificates of webhook requests with, client certificates are not verified when empty").StringVar(&config.ClientCAFile)
+	serve.Flag("client-allowed-subject", "Common name of client certificates allowed to call the webhooks, any verified client is allowed when empty").StringsVar(&config.ClientAllowedSubjects)
+	serve.Flag("metrics-secure", "Serve metrics over TLS, authenticating requests with TokenReviews and authorising them with SubjectAccessReviews").BoolVar(&config.MetricsSecure)
+	serve.Flag("metrics-auth-audience", "Audience bearer tokens for metrics must be valid for, the API server audiences are used when empty").StringsVar(&config.MetricsAuthAudiences)
+	serve.Flag("metrics-auth-namespace", "Namespace of the resource metrics requests are authorised against").StringVar(&config.MetricsAuthNamespace)
+	serve.Flag("metrics-auth-api-group", "API group of the resource metrics requests are authorised against").StringVar(&config.MetricsAuthAPIGroup)
+	serve.Flag("metrics-auth-resource", "Resource metrics requests are authorised against, the request path is authorised as a non-resource URL when empty").StringVar(&config.MetricsAuthResource)
+	serve.Flag("metrics-auth-subresource", "Subresource metrics requests are authorised against").StringVar(&config.MetricsAuthSubresource)
+	serve.Flag("metrics-auth-name", "Name of the resource metrics requests are authorised against").StringVar(&config.MetricsAuthName)
+	serve.Flag("webhook-reconcile", "Create or update the webhook configurations of the registered webhooks at startup").BoolVar(&config.WebhookReconcile)
+	serve.Flag("review-reference-access", "Deny App CRs referencing ConfigMaps or Secrets the requesting user is not allowed to get, checked with SubjectAccessReviews").BoolVar(&config.ReferenceAccessReview)
+	serve.Flag("restrict-cluster-targeting", "Deny App CRs in organization namespaces targeting a Cluster CR or referencing a kubeconfig Secret in another namespace, and changes of their cluster label by non-whitelisted users").BoolVar(&config.RestrictClusterTargeting)
+	serve.Flag("protect-managed-field", "Class of fields managed by the mutator which must not be removed or changed on updates by non-whitelisted users, one of cluster-values, psp-removal and kubeconfig").EnumsVar(&config.ProtectedFields, "cluster-values", "psp-removal", "kubeconfig")
+	serve.Flag("protect-finalizer", "Finalizer which must not be added or removed on updates, except by whitelisted service accounts").StringsVar(&config.ProtectedFinalizers)
+	serve.Flag("protect-label", "Label which must not be set, removed or changed on updates, except by whitelisted service accounts").StringsVar(&config.ProtectedLabels)
+	serve.Flag("critical-app-name", "Matcher rule of the names of critical apps whose deletion must be confirmed, matching exactly by default").StringsVar(&config.CriticalAppNames)
+	serve.Flag("critical-app-catalog", "Matcher rule of the catalogs of critical apps whose deletion must be confirmed, matching exactly by default").StringsVar(&config.CriticalAppCatalogs)
+	serve.Flag("critical-app-label", "Label of critical apps whose deletion must be confirmed, as <key> or <key>=<value>").StringsVar(&config.CriticalAppLabels)
+	ownerTeams := serve.Flag("owner-team", "Group of the members of a team as <team>=<group>, restricting changes of App CRs owned by teams to their members when set").Strings()
+	serve.Flag("policy-objects", "Watch AppAdmissionPolicy objects and enforce or audit them in addition to the flags and the policy file").BoolVar(&config.PolicyObjects)
+	serve.Flag("policy-objects-namespaced", "Also watch NamespacedAppAdmissionPolicy objects, requires --policy-objects").BoolVar(&config.PolicyObjectsNamespaced)
+	serve.Flag("break-glass-secret-name", "Name of the Secret holding the keys to verify break-glass tokens of App CRs with, break-glass tokens are not verified when empty").StringVar(&config.BreakGlassSecretName)
+	serve.Flag("break-glass-secret-namespace", "Namespace of the Secret holding the keys to verify break-glass tokens of App CRs with").StringVar(&config.BreakGlassSecretNamespace)
+	serve.Flag("break-glass-rule", "ID of a rule, or class of rules like policy, valid break-glass tokens bypass").StringsVar(&config.BreakGlassRules)
+	serve.Flag("candidate-file", "File containing a candidate security configuration to evaluate requests against in the background, recording disagreements with the live one").StringVar(&config.CandidateFile)
+	serve.Flag("audit-interval", "Interval of auditing existing apps against current admission rules, disabled when zero").Default("0s").DurationVar(&config.AuditInterval)
+	serve.Flag("audit-status-configmap-name", "Name of the ConfigMap to store audit results in").StringVar(&config.AuditStatusConfigMapName)
+	serve.Flag("audit-status-configmap-namespace", "Namespace of the ConfigMap to store audit results in").StringVar(&config.AuditStatusConfigMapNamespace)
+	serve.Flag("record-file", "JSONL file to record AdmissionReview requests and responses to, disabled when empty").StringVar(&config.RecordFile)
+	serve.Flag("record-sample-rate", "Fraction of AdmissionReviews to record, between 0 and 1").Default("1").Float64Var(&config.RecordSampleRate)
+
+	backfill := kingpin.Command(CommandBackfill, "Compute current mutation defaults for existing apps")
+	backfill.Flag("apply", "Apply the computed patches instead of only reporting them").BoolVar(&config.BackfillApply)
+	backfill.Flag("namespace", "Namespace of apps to backfill, all namespaces when empty").StringVar(&config.BackfillNamespace)
+	backfill.Flag("rate", "Maximum number of apps patched per second").Default("1").Float64Var(&config.BackfillRate)
+	backfill.Flag("selector", "Label selector of apps to backfill").StringVar(&config.BackfillSelector)
+
+	check := kingpin.Command(CommandCheck, "Run App CR manifests through the admission logic without a cluster")
+	check.Flag("fixtures", "File or directory containing objects the admission logic reads, like Catalogs or ConfigMaps").ExistingFilesOrDirsVar(&config.CheckFixtures)
+	check.Flag("user", "Name of the user submitting the App CRs").Default("kubernetes-admin").StringVar(&config.CheckUser)
+	check.Flag("group", "Group of the user submitting the App CRs").StringsVar(&config.CheckGroups)
+	check.Arg("files", "Files or directories containing App CR manifests").Required().ExistingFilesOrDirsVar(&config.CheckFiles)
+
+	replay := kingpin.Command(CommandReplay, "Replay recorded AdmissionReviews and compare the responses")
+	replay.Flag("fixtures", "File or directory containing objects the admission logic reads, like Catalogs or ConfigMaps").ExistingFilesOrDirsVar(&config.ReplayFixtures)
+	replay.Arg("file", "JSONL file with recorded AdmissionReviews").Required().ExistingFileVar(&config.ReplayFile)
+
+	manifests := kingpin.Command(CommandManifests, "Render the webhook configurations of the registered webhooks")
+	manifests.Flag("url", "Base URL the API server reaches the webhooks server at, used instead of the Service").StringVar(&config.ManifestsURL)
+	manifests.Flag("ca-file", "File containing the CA bundle to verify the serving certificate with").ExistingFileVar(&config.ManifestsCAFile)
+
+	for _, cmd := range []*kingpin.CmdClause{serve, manifests} {
+		cmd.Flag("webhook-configuration-name", "Name of the webhook configurations").Default(defaultWebhookConfigurationName).StringVar(&config.WebhookConfigurationName)
+		cmd.Flag("webhook-service-name", "Name of the Service the API server reaches the webhooks server at").Default(defaultWebhookServiceName).StringVar(&config.WebhookServiceName)
+		cmd.Flag("webhook-service-namespace", "Namespace of the Service the API server reaches the webhooks server at").Default(defaultWebhookServiceNamespace).StringVar(&config.WebhookServiceNamespace)
+		cmd.Flag("webhook-cert-manager-certificate", "Namespace and name of the cert-manager Certificate to inject the CA bundle from, e.g. giantswarm/app-admission-controller-certificates").StringVar(&config.WebhookCertManagerCertificate)
+	}
+
+	config.Command = kingpin.Parse()
+
+	if config.Command == CommandServe {
+		if config.SelfSignedCert && config.SelfManagedTLS {
+			return Config{}, microerror.Maskf(invalidFlagError, "--self-signed-cert must not be used together with --self-managed-tls")
+		}
+		if config.SelfSignedCert && (config.CertFile != "" || config.KeyFile != "") {
+			return Config{}, microerror.Maskf(invalidFlagError, "--self-signed-cert must not be used together with --tls-cert-file and --tls-key-file")
+		}
+		if !config.SelfSignedCert && (config.CertFile == "" || config.KeyFile == "") {
+			return Config{}, microerror.Maskf(invalidFlagError, "--tls-cert-file and --tls-key-file must not be empty unless --self-signed-cert is used")
+		}
+		if config.DevWebhookInstall && config.DevWebhookURL == "" {
+			return Config{}, microerror.Maskf(invalidFlagError, "--dev-webhook-url must not be empty when --dev-webhook-install is used")
+		}
+		if config.PolicyObjectsNamespaced && !config.PolicyObjects {
+			return Config{}, microerror.Maskf(invalidFlagError, "--policy-objects-namespaced must not be used without --policy-objects")
+		}
+	}
+
+	// Create a new k8sclient that is used by all admitters. The check,
+	// replay and manifests commands run without a cluster, with clients
+	// serving fixture objects.
+	if config.Command != CommandCheck && config.Command != CommandReplay && config.Command != CommandManifests {
+		restConfig, err := newRestConfig(config.Kubeconfig, config.KubeContext)
 		if err != nil {
 			return Config{}, microerror.Mask(err)
 		}
@@ -82,28 +332,12 @@ func Parse() (Config, error) {
 			RestConfig: restConfig,
 		}
 
-		k8sClient, err = k8sclient.NewClients(c)
+		config.K8sClient, err = k8sclient.NewClients(c)
 		if err != nil {
 			return Config{}, microerror.Mask(err)
 		}
-		config.K8sClient = k8sClient
 	}
 
-	kingpin.Flag("address", "The address to listen on").Default(defaultAddress).StringVar(&config.Address)
-	kingpin.Flag("metrics-address", "The metrics address for Prometheus").Default(defaultMetricsAddress).StringVar(&config.MetricsAddress)
-	kingpin.Flag("tls-cert-file", "File containing the certificate for HTTPS").Required().StringVar(&config.CertFile)
-	kingpin.Flag("tls-key-file", "File containing the private key for HTTPS").Required().StringVar(&config.KeyFile)
-	kingpin.Flag("provider", "Provider of the management cluster. One of aws, azure, kvm").Required().StringVar(&config.Provider)
-
-	kingpin.Flag("whitelist-group", "Whitelisted group").StringsVar(&config.GroupWhitelist)
-	kingpin.Flag("whitelist-user", "Whitelisted user").StringsVar(&config.UserWhitelist)
-	kingpin.Flag("blacklist-app", "Blacklisted apps").StringsVar(&config.AppBlacklist)
-	kingpin.Flag("blacklist-catalog", "Blacklisted catalogs").StringsVar(&config.CatalogBlacklist)
-	kingpin.Flag("blacklist-namespace", "Blacklisted namespaces").StringsVar(&config.NamespaceBlacklist)
-	kingpin.Flag("psp-config-file", "File containing PSP patch configuration").StringVar(&config.PSPConfigFile)
-
-	kingpin.Parse()
-
 	config.PSPPatches = []ConfigPatch{}
 
 	if config.PSPConfigFile != "" {
@@ -120,5 +354,67 @@ func Parse() (Config, error) {
 		config.PSPPatches = patches
 	}
 
+	if config.PolicyFile != "" {
+		config.Policies, err = policy.Load(config.PolicyFile)
+		if err != nil {
+			return Config{}, microerror.Mask(err)
+		}
+	}
+
+	config.RuleModes = map[string]enforcement.Mode{}
+	for rule, m := range *ruleModes {
+		config.RuleModes[rule], err = enforcement.ParseMode(m)
+		if err != nil {
+			return Config{}, microerror.Mask(err)
+		}
+	}
+	config.EnforcementOverride = enforcement.Mode(*enforcementOverride)
+
+	if len(*ownerTeams) > 0 {
+		config.OwnerTeams = map[string][]string{}
+		for _, t := range *ownerTeams {
+			team, group, ok := strings.Cut(t, "=")
+			if !ok || team == "" || group == "" {
+				return Config{}, microerror.Maskf(invalidFlagError, "--owner-team %#q must be formatted as <team>=<group>", t)
+			}
+			config.OwnerTeams[team] = append(config.OwnerTeams[team], group)
+		}
+	}
+
+	if config.RuleFile != "" {
+		config.Rules, err = expression.Load(config.RuleFile)
+		if err != nil {
+			return Config{}, microerror.Mask(err)
+		}
+	}
+
 	return config, nil
 }
+
+// newRestConfig returns the in-cluster configuration unless a kubeconfig
+// file or context is given. Without an explicit file the kubeconfig is
+// loaded like kubectl does it, i.e. from $KUBECONFIG or ~/.kube/config.
+func newRestConfig(kubeconfig, context string) (*restclient.Config, error) {
+	if kubeconfig == "" && context == "" {
+		restConfig, err := restclient.InClusterConfig()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return restConfig, nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: context,
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return restConfig, nil
+}
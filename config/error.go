@@ -0,0 +1,14 @@
+package config
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidFlagError = &microerror.Error{
+	Kind: "invalidFlagError",
+}
+
+// IsInvalidFlag asserts invalidFlagError.
+func IsInvalidFlag(err error) bool {
+	return microerror.Cause(err) == invalidFlagError
+}
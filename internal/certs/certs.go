@@ -0,0 +1,217 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// SelfSigned generates a self-signed serving certificate valid for the given
+// DNS names and IP addresses. The certificate is its own CA, so it can be
+// used as the caBundle of webhook configurations. The certificate and the
+// private key are returned PEM encoded.
+func SelfSigned(hosts []string, validity time.Duration) ([]byte, []byte, error) {
+	if len(hosts) == 0 {
+		return nil, nil, microerror.Maskf(invalidConfigError, "hosts must not be empty")
+	}
+
+	template, err := newTemplate(hosts[0], validity)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	template.IsCA = true
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	setHosts(template, hosts)
+
+	return generate(template, nil, nil)
+}
+
+// NewCA generates a self-signed CA certificate. The certificate and the
+// private key are returned PEM encoded.
+func NewCA(commonName string, validity time.Duration) ([]byte, []byte, error) {
+	template, err := newTemplate(commonName, validity)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	template.IsCA = true
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
+
+	return generate(template, nil, nil)
+}
+
+// NewServing generates a serving certificate valid for the given DNS names
+// and IP addresses, signed by the given PEM encoded CA. The certificate and
+// the private key are returned PEM encoded.
+func NewServing(caCertPEM, caKeyPEM []byte, hosts []string, validity time.Duration) ([]byte, []byte, error) {
+	if len(hosts) == 0 {
+		return nil, nil, microerror.Maskf(invalidConfigError, "hosts must not be empty")
+	}
+
+	ca, err := ParseCertificate(caCertPEM)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	caKey, err := ParsePrivateKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	template, err := newTemplate(hosts[0], validity)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	setHosts(template, hosts)
+
+	return generate(template, ca, caKey)
+}
+
+// NewClient generates a client certificate with the given common name,
+// signed by the given PEM encoded CA. The certificate and the private key
+// are returned PEM encoded.
+func NewClient(caCertPEM, caKeyPEM []byte, commonName string, validity time.Duration) ([]byte, []byte, error) {
+	ca, err := ParseCertificate(caCertPEM)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	caKey, err := ParsePrivateKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	template, err := newTemplate(commonName, validity)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	return generate(template, ca, caKey)
+}
+
+// ParseCertificates parses all PEM encoded certificates of the given
+// bundle.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, microerror.Maskf(invalidPEMError, "no certificates found")
+	}
+
+	return certs, nil
+}
+
+// ParseCertificate parses the first PEM encoded certificate of the given
+// data.
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return certs[0], nil
+}
+
+// ParsePrivateKey parses a PEM encoded EC private key.
+func ParsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, microerror.Maskf(invalidPEMError, "no EC private key found")
+	}
+
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return key, nil
+}
+
+func newTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: commonName,
+		},
+		NotBefore: now.Add(-time.Minute),
+		NotAfter:  now.Add(validity),
+
+		BasicConstraintsValid: true,
+	}
+
+	return template, nil
+}
+
+func setHosts(template *x509.Certificate, hosts []string) {
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+}
+
+// generate creates the certificate from the template with a new key. The
+// certificate is self-signed when no parent is given.
+func generate(template, parent *x509.Certificate, parentKey crypto.Signer) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,63 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func Test_SelfSigned(t *testing.T) {
+	tests := []struct {
+		name          string
+		hosts         []string
+		verifiedHosts []string
+		errorMatcher  func(error) bool
+	}{
+		{
+			name:          "case 0: DNS names and IP addresses",
+			hosts:         []string{"localhost", "127.0.0.1", "host.docker.internal"},
+			verifiedHosts: []string{"localhost", "127.0.0.1", "host.docker.internal"},
+		},
+		{
+			name:         "case 1: no hosts",
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			certPEM, keyPEM, err := SelfSigned(tc.hosts, time.Hour)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			case err != nil:
+				return
+			}
+
+			pair, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			cert, err := x509.ParseCertificate(pair.Certificate[0])
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(cert)
+
+			for _, h := range tc.verifiedHosts {
+				_, err = cert.Verify(x509.VerifyOptions{DNSName: h, Roots: roots})
+				if err != nil {
+					t.Fatalf("error == %#v, want nil for host %#q", err, h)
+				}
+			}
+		})
+	}
+}
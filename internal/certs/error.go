@@ -0,0 +1,23 @@
+package certs
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidPEMError = &microerror.Error{
+	Kind: "invalidPEMError",
+}
+
+// IsInvalidPEM asserts invalidPEMError.
+func IsInvalidPEM(err error) bool {
+	return microerror.Cause(err) == invalidPEMError
+}
@@ -0,0 +1,32 @@
+package expression
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidRuleError = &microerror.Error{
+	Kind: "invalidRuleError",
+}
+
+// IsInvalidRule asserts invalidRuleError.
+func IsInvalidRule(err error) bool {
+	return microerror.Cause(err) == invalidRuleError
+}
+
+var ruleViolationError = &microerror.Error{
+	Kind: "ruleViolationError",
+}
+
+// IsRuleViolation asserts ruleViolationError.
+func IsRuleViolation(err error) bool {
+	return microerror.Cause(err) == ruleViolationError
+}
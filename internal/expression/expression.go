@@ -0,0 +1,368 @@
+// Package expression validates App CRs with rules written as CEL
+// expressions, so that one-off checks of teams do not need Go code.
+package expression
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+	authv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/metrics"
+)
+
+const (
+	// costLimit bounds the cost of evaluating a single expression, so that
+	// rules can't stall admission.
+	costLimit = 1000000
+
+	resultAllowed = "allowed"
+	resultDenied  = "denied"
+	resultError   = "error"
+	resultSkipped = "skipped"
+
+	ruleViolationTemplate = "app %#q in namespace %#q violates rule %#q: %s"
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+
+	Rules []Rule
+	// Silent disables the evaluation metrics and the violation logs, for
+	// evaluations which are no admissions, e.g. of candidates.
+	Silent bool
+}
+
+// Evaluator evaluates App CRs against CEL rules, which are compiled once in
+// New.
+type Evaluator struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+
+	rules []rule
+	// namespaced is true when any rule uses namespaceObject, which is
+	// only read then.
+	namespaced bool
+	silent     bool
+}
+
+// rule is a Rule with its compiled programs.
+type rule struct {
+	Rule
+
+	matchConditions   []cel.Program
+	expression        cel.Program
+	messageExpression cel.Program
+	namespaced        bool
+}
+
+func New(config Config) (*Evaluator, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	env, err := newEnv()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	e := &Evaluator{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		silent: config.Silent,
+	}
+
+	names := map[string]bool{}
+	for _, r := range config.Rules {
+		if names[r.Name] {
+			return nil, microerror.Maskf(invalidRuleError, "rule %#q is defined more than once", r.Name)
+		}
+		names[r.Name] = true
+
+		compiled, err := compile(env, r)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		e.rules = append(e.rules, compiled)
+		e.namespaced = e.namespaced || compiled.namespaced
+	}
+
+	return e, nil
+}
+
+// Load reads rules from the YAML file.
+func Load(file string) ([]Rule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var rules []Rule
+	err = yaml.UnmarshalStrict(data, &rules)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%s: %s", file, err)
+	}
+
+	return rules, nil
+}
+
+// Evaluate returns a ruleViolationError when the App CR violates any of the
+// rules. currentApp is the App CR being updated, nil for other operations.
+// Expressions failing to evaluate count as violations. Violations of
+// several rules are joined, each marked with the ID and the default mode of
+// the rule, see the enforcement package.
+func (e *Evaluator) Evaluate(ctx context.Context, operation string, app v1alpha1.App, currentApp *v1alpha1.App, userInfo authv1.UserInfo) error {
+	if len(e.rules) == 0 {
+		return nil
+	}
+
+	vars, err := e.variables(ctx, operation, app, currentApp, userInfo)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var violations []error
+	for _, r := range e.rules {
+		start := time.Now()
+		result, message := r.evaluate(ctx, vars)
+		if !e.silent {
+			metrics.RuleEvaluationDuration.WithLabelValues(r.Name).Observe(time.Since(start).Seconds())
+			metrics.RuleEvaluations.WithLabelValues(r.Name, result).Inc()
+		}
+
+		if result == resultAllowed || result == resultSkipped {
+			continue
+		}
+
+		err := microerror.Maskf(ruleViolationError, ruleViolationTemplate, app.Name, app.Namespace, r.Name, message)
+		if !e.silent {
+			e.logger.Errorf(ctx, err, "found violation of rule %#q by app %#q in namespace %#q", r.Name, app.Name, app.Namespace)
+		}
+		violations = append(violations, enforcement.WithRuleMode(enforcement.ExpressionRule(r.Name), modes[r.Enforcement], err))
+	}
+
+	switch len(violations) {
+	case 0:
+		return nil
+	case 1:
+		return microerror.Mask(violations[0])
+	default:
+		return microerror.Mask(errors.Join(violations...))
+	}
+}
+
+// evaluate returns the result of the rule and, unless allowed or skipped,
+// the message explaining it.
+func (r rule) evaluate(ctx context.Context, vars map[string]interface{}) (string, string) {
+	for _, p := range r.matchConditions {
+		matched, err := evalBool(ctx, p, vars)
+		if err != nil {
+			return resultError, fmt.Sprintf("match condition failed: %s", err)
+		}
+		if !matched {
+			return resultSkipped, ""
+		}
+	}
+
+	valid, err := evalBool(ctx, r.expression, vars)
+	if err != nil {
+		return resultError, fmt.Sprintf("expression failed: %s", err)
+	}
+	if valid {
+		return resultAllowed, ""
+	}
+
+	message := r.Message
+	if r.messageExpression != nil {
+		val, _, err := r.messageExpression.ContextEval(ctx, vars)
+		if s, ok := val.(types.String); err == nil && ok && s != "" {
+			message = string(s)
+		}
+	}
+	if message == "" {
+		message = fmt.Sprintf("failed expression %s", r.Expression)
+	}
+
+	return resultDenied, message
+}
+
+// variables returns the variables of the expressions. The namespace is
+// only read when a rule uses it.
+func (e *Evaluator) variables(ctx context.Context, operation string, app v1alpha1.App, currentApp *v1alpha1.App, userInfo authv1.UserInfo) (map[string]interface{}, error) {
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&app)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var oldObject map[string]interface{}
+	if currentApp != nil {
+		oldObject, err = runtime.DefaultUnstructuredConverter.ToUnstructured(currentApp)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	user, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&userInfo)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var namespaceObject map[string]interface{}
+	if e.namespaced {
+		ns, err := e.k8sClient.K8sClient().CoreV1().Namespaces().Get(ctx, app.Namespace, metav1.GetOptions{})
+		if err == nil {
+			namespaceObject, err = runtime.DefaultUnstructuredConverter.ToUnstructured(ns)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		} else if !apierrors.IsNotFound(err) {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	vars := map[string]interface{}{
+		"object":    object,
+		"oldObject": nullable(oldObject),
+		"request": map[string]interface{}{
+			"operation": operation,
+			"userInfo":  user,
+		},
+		"namespaceObject": nullable(namespaceObject),
+	}
+
+	return vars, nil
+}
+
+func newEnv() (*cel.Env, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("oldObject", cel.DynType),
+		cel.Variable("request", cel.DynType),
+		cel.Variable("namespaceObject", cel.DynType),
+		cel.OptionalTypes(),
+		ext.Strings(),
+		ext.Lists(),
+		ext.Sets(),
+	)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return env, nil
+}
+
+func compile(env *cel.Env, r Rule) (rule, error) {
+	if r.Name == "" {
+		return rule{}, microerror.Maskf(invalidRuleError, "rule name must not be empty")
+	}
+	if r.Expression == "" {
+		return rule{}, microerror.Maskf(invalidRuleError, "expression of rule %#q must not be empty", r.Name)
+	}
+
+	if r.Enforcement == "" {
+		r.Enforcement = EnforcementEnforce
+	}
+	if _, ok := modes[r.Enforcement]; !ok {
+		return rule{}, microerror.Maskf(invalidRuleError, "enforcement %#q of rule %#q is not one of %#q, %#q, %#q and %#q", r.Enforcement, r.Name, EnforcementEnforce, EnforcementWarn, EnforcementAudit, EnforcementDryRun)
+	}
+
+	result := rule{Rule: r}
+
+	for _, c := range r.MatchConditions {
+		p, namespaced, err := program(env, r.Name, c, cel.BoolType)
+		if err != nil {
+			return rule{}, microerror.Mask(err)
+		}
+		result.matchConditions = append(result.matchConditions, p)
+		result.namespaced = result.namespaced || namespaced
+	}
+
+	p, namespaced, err := program(env, r.Name, r.Expression, cel.BoolType)
+	if err != nil {
+		return rule{}, microerror.Mask(err)
+	}
+	result.expression = p
+	result.namespaced = result.namespaced || namespaced
+
+	if r.MessageExpression != "" {
+		p, namespaced, err := program(env, r.Name, r.MessageExpression, cel.StringType)
+		if err != nil {
+			return rule{}, microerror.Mask(err)
+		}
+		result.messageExpression = p
+		result.namespaced = result.namespaced || namespaced
+	}
+
+	return result, nil
+}
+
+// program compiles the expression, which has to evaluate to the given type,
+// and reports whether it uses namespaceObject.
+func program(env *cel.Env, name, expr string, t *cel.Type) (cel.Program, bool, error) {
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		return nil, false, microerror.Maskf(invalidRuleError, "rule %#q: %s", name, issues.Err())
+	}
+	if !ast.OutputType().IsExactType(t) && !ast.OutputType().IsExactType(cel.DynType) {
+		return nil, false, microerror.Maskf(invalidRuleError, "rule %#q: expression %#q must evaluate to %s, not %s", name, expr, t, ast.OutputType())
+	}
+
+	p, err := env.Program(ast, cel.CostLimit(costLimit), cel.InterruptCheckFrequency(100))
+	if err != nil {
+		return nil, false, microerror.Maskf(invalidRuleError, "rule %#q: %s", name, err)
+	}
+
+	namespaced := false
+	for _, ref := range ast.NativeRep().ReferenceMap() {
+		if ref.Name == "namespaceObject" {
+			namespaced = true
+		}
+	}
+
+	return p, namespaced, nil
+}
+
+func evalBool(ctx context.Context, p cel.Program, vars map[string]interface{}) (bool, error) {
+	val, _, err := p.ContextEval(ctx, vars)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	b, ok := val.(types.Bool)
+	if !ok {
+		return false, microerror.Maskf(invalidRuleError, "expression evaluated to %s, not bool", val.Type())
+	}
+
+	return bool(b), nil
+}
+
+// nullable returns nil for null when the object is nil, as a nil map would
+// be an empty map in CEL.
+func nullable(obj map[string]interface{}) interface{} {
+	if obj == nil {
+		return nil
+	}
+
+	return obj
+}
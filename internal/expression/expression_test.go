@@ -0,0 +1,205 @@
+package expression
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/errors"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+)
+
+func Test_Evaluator_Evaluate(t *testing.T) {
+	app := v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hello-world",
+			Namespace: "org-acme",
+		},
+		Spec: v1alpha1.AppSpec{
+			Catalog: "giantswarm",
+			Name:    "hello-world",
+			Version: "1.0.0-rc1",
+		},
+	}
+
+	withTimeout := *app.DeepCopy()
+	withTimeout.Spec.Install.Timeout = &metav1.Duration{}
+
+	currentApp := *app.DeepCopy()
+	currentApp.Spec.Version = "0.9.0"
+
+	enforcer, err := enforcement.New(enforcement.Config{Logger: microloggertest.New()})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	tests := []struct {
+		name         string
+		rules        []Rule
+		operation    string
+		app          v1alpha1.App
+		currentApp   *v1alpha1.App
+		errorMatcher errors.Matcher
+	}{
+		{
+			name: "case 0: satisfied rule admits",
+			rules: []Rule{
+				{Name: "catalog", Expression: "object.spec.catalog == 'giantswarm'"},
+			},
+			app: app,
+		},
+		{
+			name: "case 1: violated rule rejects",
+			rules: []Rule{
+				{Name: "timeout", Expression: "has(object.spec.install) && has(object.spec.install.timeout)", Message: "install timeout must be set"},
+			},
+			app:          app,
+			errorMatcher: IsRuleViolation,
+		},
+		{
+			name: "case 2: rule admits once satisfied",
+			rules: []Rule{
+				{Name: "timeout", Expression: "has(object.spec.install) && has(object.spec.install.timeout)"},
+			},
+			app: withTimeout,
+		},
+		{
+			name: "case 3: unmatched rule is skipped",
+			rules: []Rule{
+				{Name: "prerelease", MatchConditions: []string{"object.metadata.namespace.startsWith('org-prod')"}, Expression: "!object.spec.version.contains('-')"},
+			},
+			app: app,
+		},
+		{
+			name: "case 4: rule matched by namespace labels rejects",
+			rules: []Rule{
+				{Name: "prerelease", MatchConditions: []string{"namespaceObject.metadata.labels['env'] == 'prod'"}, Expression: "!object.spec.version.contains('-')"},
+			},
+			app:          app,
+			errorMatcher: IsRuleViolation,
+		},
+		{
+			name: "case 5: audited rule is marked with its mode",
+			rules: []Rule{
+				{Name: "catalog", Expression: "object.spec.catalog == 'other'", Enforcement: EnforcementAudit},
+			},
+			app: app,
+			errorMatcher: func(err error) bool {
+				return IsRuleViolation(err) && enforcer.Mode(err) == enforcement.Audit && enforcement.Rule(err) == "rule/catalog"
+			},
+		},
+		{
+			name: "case 6: failing expression rejects",
+			rules: []Rule{
+				{Name: "missing", Expression: "object.spec.install.timeout != ''"},
+			},
+			app:          app,
+			errorMatcher: IsRuleViolation,
+		},
+		{
+			name: "case 7: oldObject is null on create",
+			rules: []Rule{
+				{Name: "version", Expression: "oldObject == null || oldObject.spec.version == object.spec.version"},
+			},
+			operation: "CREATE",
+			app:       app,
+		},
+		{
+			name: "case 8: oldObject is set on update",
+			rules: []Rule{
+				{Name: "version", Expression: "oldObject == null || oldObject.spec.version == object.spec.version", MessageExpression: "'version must not change from ' + oldObject.spec.version"},
+			},
+			operation:    "UPDATE",
+			app:          app,
+			currentApp:   &currentApp,
+			errorMatcher: IsRuleViolation,
+		},
+		{
+			name: "case 9: rule on user info",
+			rules: []Rule{
+				{Name: "user", Expression: "request.userInfo.groups.exists(g, g == 'admins') || object.spec.catalog != 'giantswarm'"},
+			},
+			app:          app,
+			errorMatcher: IsRuleViolation,
+		},
+	}
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		K8sClient: clientgofake.NewClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "org-acme", Labels: map[string]string{"env": "prod"}}},
+		),
+	})
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := New(Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Rules:     tc.rules,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			userInfo := authv1.UserInfo{Username: "jane", Groups: []string{"developers"}}
+
+			err = e.Evaluate(context.Background(), tc.operation, tc.app, tc.currentApp, userInfo)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_New(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+	}{
+		{
+			name:  "case 0: syntax error",
+			rules: []Rule{{Name: "broken", Expression: "object.spec.name =="}},
+		},
+		{
+			name:  "case 1: expression not evaluating to bool",
+			rules: []Rule{{Name: "string", Expression: "'hello'"}},
+		},
+		{
+			name:  "case 2: duplicate name",
+			rules: []Rule{{Name: "a", Expression: "true"}, {Name: "a", Expression: "true"}},
+		},
+		{
+			name:  "case 3: unknown enforcement",
+			rules: []Rule{{Name: "a", Expression: "true", Enforcement: "Sometimes"}},
+		},
+		{
+			name:  "case 4: unknown variable",
+			rules: []Rule{{Name: "a", Expression: "params.enabled"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(Config{
+				K8sClient: k8sclienttest.NewClients(k8sclienttest.ClientsConfig{}),
+				Logger:    microloggertest.New(),
+				Rules:     tc.rules,
+			})
+			if !IsInvalidRule(err) {
+				t.Fatalf("error == %#v, want invalid rule", err)
+			}
+		})
+	}
+}
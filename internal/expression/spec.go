@@ -0,0 +1,57 @@
+package expression
+
+import (
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+)
+
+// Enforcement is the way violations of a rule are handled.
+type Enforcement string
+
+const (
+	// EnforcementEnforce rejects App CRs violating the rule.
+	EnforcementEnforce Enforcement = "Enforce"
+	// EnforcementWarn admits App CRs violating the rule with an admission
+	// warning.
+	EnforcementWarn Enforcement = "Warn"
+	// EnforcementAudit admits App CRs violating the rule, but logs the
+	// violation and counts it in a metric.
+	EnforcementAudit Enforcement = "Audit"
+	// EnforcementDryRun admits App CRs violating the rule, and only counts
+	// the violation in a metric.
+	EnforcementDryRun Enforcement = "DryRun"
+)
+
+// modes maps the enforcements of rules to the enforcement modes.
+var modes = map[Enforcement]enforcement.Mode{
+	EnforcementEnforce: enforcement.Enforce,
+	EnforcementWarn:    enforcement.Warn,
+	EnforcementAudit:   enforcement.Audit,
+	EnforcementDryRun:  enforcement.DryRun,
+}
+
+// Rule is a CEL validation rule for App CRs. Expressions have access to the
+// following variables:
+//
+//   - object is the App CR.
+//   - oldObject is the App CR being updated, null for other operations.
+//   - request holds the operation and the userInfo of the request.
+//   - namespaceObject is the namespace of the App CR, null when it does
+//     not exist.
+type Rule struct {
+	Name string `json:"name"`
+
+	// MatchConditions are expressions which all have to evaluate to true
+	// for the rule to apply to the App CR.
+	MatchConditions []string `json:"matchConditions,omitempty"`
+	// Expression has to evaluate to true for the App CR to be valid.
+	Expression string `json:"expression"`
+
+	// Message is returned when the App CR is invalid. MessageExpression
+	// takes precedence, and is an expression evaluating to a string.
+	Message           string `json:"message,omitempty"`
+	MessageExpression string `json:"messageExpression,omitempty"`
+
+	// Enforcement defaults to Enforce. It can be overridden by the ID of
+	// the rule, rule/<name>, see the enforcement package.
+	Enforcement Enforcement `json:"enforcement,omitempty"`
+}
@@ -0,0 +1,261 @@
+// Package fixture builds in-memory Kubernetes clients from object manifests,
+// so that the admission logic can run without a cluster.
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/microerror"
+	releases "github.com/giantswarm/releases/sdk/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+)
+
+// Scheme knows all the object kinds the admission logic reads.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	for _, add := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		v1alpha1.AddToScheme,
+		capiv1beta1.AddToScheme,
+		releases.AddToScheme,
+	} {
+		err := add(Scheme)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// NewClients returns in-memory clients serving the given objects.
+func NewClients(objs []runtime.Object) *k8sclienttest.Clients {
+	var coreObjs []runtime.Object
+	for _, obj := range objs {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if clientgoscheme.Scheme.Recognizes(gvk) {
+			coreObjs = append(coreObjs, obj.DeepCopyObject())
+		}
+	}
+
+	ctrlClient := fake.NewClientBuilder().
+		WithScheme(Scheme).
+		WithRuntimeObjects(objs...).
+		WithIndex(&v1alpha1.App{}, "metadata.name", func(obj client.Object) []string {
+			return []string{obj.GetName()}
+		}).
+		Build()
+
+	return k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		CtrlClient: &ctrlClientWrapper{ctrlClient},
+		K8sClient:  clientgofake.NewClientset(coreObjs...),
+	})
+}
+
+// Load reads all objects from YAML or JSON manifests found in the given
+// files or directories. Directories are not walked recursively.
+func Load(paths ...string) ([]runtime.Object, error) {
+	var objs []runtime.Object
+
+	for _, p := range paths {
+		files, err := manifestFiles(p)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, f := range files {
+			data, err := os.ReadFile(f) //nolint:gosec
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			decoded, err := Decode(data)
+			if err != nil {
+				return nil, microerror.Maskf(invalidManifestError, "%s: %s", f, err)
+			}
+
+			objs = append(objs, decoded...)
+		}
+	}
+
+	return objs, nil
+}
+
+// Decode decodes all objects from the possibly multi-document YAML or JSON
+// data into their typed representation.
+func Decode(data []byte) ([]runtime.Object, error) {
+	var objs []runtime.Object
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		err := decoder.Decode(&u.Object)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+
+		gvk := u.GroupVersionKind()
+
+		obj, err := Scheme.New(gvk)
+		if err != nil {
+			return nil, microerror.Maskf(invalidManifestError, "unsupported kind %#q", gvk.String())
+		}
+
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if ext == ".yaml" || ext == ".yml" || ext == ".json" {
+			files = append(files, filepath.Join(path, e.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+// ctrlClientWrapper applies field selectors using operators the fake client
+// does not support, i.e. '!=', by filtering the listed objects in memory.
+type ctrlClientWrapper struct {
+	client.Client
+}
+
+func (c *ctrlClientWrapper) List(ctx context.Context, obj client.ObjectList, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	var excluded []fields.Requirement
+	if listOpts.FieldSelector != nil {
+		var requirements []string
+		for _, r := range listOpts.FieldSelector.Requirements() {
+			switch r.Operator {
+			case selection.Equals, selection.DoubleEquals:
+				requirements = append(requirements, fmt.Sprintf("%s%s%s", r.Field, r.Operator, r.Value))
+			case selection.NotEquals:
+				excluded = append(excluded, r)
+			}
+		}
+
+		selector, err := fields.ParseSelector(strings.Join(requirements, ","))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if selector.Empty() {
+			listOpts.FieldSelector = nil
+		} else {
+			listOpts.FieldSelector = selector
+		}
+	}
+
+	err := c.Client.List(ctx, obj, &listOpts)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(excluded) == 0 {
+		return nil
+	}
+
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var filtered []runtime.Object
+	for _, item := range items {
+		matches := true
+		for _, r := range excluded {
+			value, err := fieldValue(item, r.Field)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			if value == r.Value {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			filtered = append(filtered, item)
+		}
+	}
+
+	err = meta.SetList(obj, filtered)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// fieldValue returns the value of the field of the object, given by its
+// path like in field selectors, e.g. metadata.name.
+func fieldValue(obj runtime.Object, field string) (string, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	value, found, err := unstructured.NestedFieldNoCopy(content, strings.Split(field, ".")...)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if !found || value == nil {
+		return "", nil
+	}
+
+	return fmt.Sprint(value), nil
+}
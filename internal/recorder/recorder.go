@@ -43,9 +43,25 @@ func (r *K8sEventsRecorder) Emit(ctx context.Context, obj pkgruntime.Object, rea
 	r.Eventf(obj, corev1.EventTypeNormal, reason, upper(message), args...)
 }
 
+// EmitWarning writes warning events, like policy violations found for already
+// existing objects.
+func (r *K8sEventsRecorder) EmitWarning(ctx context.Context, obj pkgruntime.Object, reason, message string, args ...interface{}) {
+	r.Eventf(obj, corev1.EventTypeWarning, reason, upper(message), args...)
+}
+
 // upper is a helper function to uppercase first letter of the event message
 func upper(in string) string {
 	out := []rune(in)
 	out[0] = unicode.ToUpper(out[0])
 	return string(out)
 }
+
+// Discard drops all events, e.g. of evaluations which must not be visible
+// to users.
+type Discard struct{}
+
+func (Discard) Emit(ctx context.Context, obj pkgruntime.Object, reason, message string, args ...interface{}) {
+}
+
+func (Discard) EmitWarning(ctx context.Context, obj pkgruntime.Object, reason, message string, args ...interface{}) {
+}
@@ -0,0 +1,173 @@
+// Package access reviews whether users submitting App CRs may read the
+// objects the App CRs reference, as app-operator reads them with its own
+// privileges.
+package access
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/app/v8/pkg/key"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	authv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	accessDeniedTemplate = "user %#q is not allowed to get %s %#q in namespace %#q referenced in %s"
+)
+
+// Reference is an object referenced by an App CR.
+type Reference struct {
+	// Field is the App CR field the reference is set in, e.g.
+	// .spec.userConfig.secret.
+	Field     string
+	Resource  string
+	Name      string
+	Namespace string
+}
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+}
+
+// Reviewer issues SubjectAccessReviews for users against the objects
+// referenced by App CRs.
+type Reviewer struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+}
+
+func New(config Config) (*Reviewer, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	r := &Reviewer{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+	}
+
+	return r, nil
+}
+
+// Review returns an accessDeniedError when the user may not get one of the
+// objects referenced by the App CR. On updates only references which are
+// not set in the current App CR are reviewed, so that users can keep
+// updating App CRs whose references were set by someone else.
+func (r *Reviewer) Review(ctx context.Context, app v1alpha1.App, currentApp *v1alpha1.App, userInfo authv1.UserInfo) error {
+	current := map[Reference]bool{}
+	if currentApp != nil {
+		for _, ref := range References(*currentApp) {
+			current[ref] = true
+		}
+	}
+
+	for _, ref := range References(app) {
+		if current[ref] {
+			continue
+		}
+
+		allowed, err := r.allowed(ctx, ref, userInfo)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if !allowed {
+			return microerror.Maskf(accessDeniedError, accessDeniedTemplate, userInfo.Username, singular(ref.Resource), ref.Name, ref.Namespace, ref.Field)
+		}
+	}
+
+	return nil
+}
+
+// References returns the ConfigMaps and Secrets referenced by the App CR.
+func References(app v1alpha1.App) []Reference {
+	var refs []Reference
+
+	add := func(field, resource, name, namespace string) {
+		if name == "" {
+			return
+		}
+
+		refs = append(refs, Reference{
+			Field:     field,
+			Resource:  resource,
+			Name:      name,
+			Namespace: namespace,
+		})
+	}
+
+	add(".spec.config.configMap", "configmaps", key.AppConfigMapName(app), key.AppConfigMapNamespace(app))
+	add(".spec.config.secret", "secrets", key.AppSecretName(app), key.AppSecretNamespace(app))
+	add(".spec.userConfig.configMap", "configmaps", key.UserConfigMapName(app), key.UserConfigMapNamespace(app))
+	add(".spec.userConfig.secret", "secrets", key.UserSecretName(app), key.UserSecretNamespace(app))
+
+	if !key.InCluster(app) {
+		add(".spec.kubeConfig.secret", "secrets", key.KubeConfigSecretName(app), key.KubeConfigSecretNamespace(app))
+	}
+
+	for i, extraConfig := range key.ExtraConfigs(app) {
+		resource := "configmaps"
+		if extraConfig.Kind == "secret" {
+			resource = "secrets"
+		}
+
+		add(fmt.Sprintf(".spec.extraConfigs[%d]", i), resource, extraConfig.Name, extraConfig.Namespace)
+	}
+
+	return refs
+}
+
+func (r *Reviewer) allowed(ctx context.Context, ref Reference, userInfo authv1.UserInfo) (bool, error) {
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: ref.Namespace,
+				Verb:      "get",
+				Resource:  ref.Resource,
+				Name:      ref.Name,
+			},
+			User:   userInfo.Username,
+			Groups: userInfo.Groups,
+			UID:    userInfo.UID,
+		},
+	}
+
+	if len(userInfo.Extra) > 0 {
+		review.Spec.Extra = map[string]authorizationv1.ExtraValue{}
+		for k, v := range userInfo.Extra {
+			review.Spec.Extra[k] = authorizationv1.ExtraValue(v)
+		}
+	}
+
+	review, err := r.k8sClient.K8sClient().AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if !review.Status.Allowed {
+		r.logger.Debugf(ctx, "user %#q is not allowed to get %s %#q in namespace %#q: %s", userInfo.Username, ref.Resource, ref.Name, ref.Namespace, review.Status.Reason)
+	}
+
+	return review.Status.Allowed, nil
+}
+
+func singular(resource string) string {
+	switch resource {
+	case "configmaps":
+		return "ConfigMap"
+	case "secrets":
+		return "Secret"
+	default:
+		return resource
+	}
+}
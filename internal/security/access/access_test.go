@@ -0,0 +1,97 @@
+package access
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	authv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func Test_Reviewer_Review(t *testing.T) {
+	tests := []struct {
+		name          string
+		app           v1alpha1.App
+		currentApp    *v1alpha1.App
+		expectedError bool
+	}{
+		{
+			name:          "case 0: readable references",
+			app:           newApp("org-acme", "acme-user-values", "org-acme"),
+			expectedError: false,
+		},
+		{
+			name:          "case 1: unreadable kubeconfig of another org",
+			app:           newApp("org-acme", "acme-user-values", "org-other"),
+			expectedError: true,
+		},
+		{
+			name:          "case 2: unchanged unreadable reference on update",
+			app:           newApp("org-acme", "acme-user-values-v2", "org-other"),
+			currentApp:    newAppPtr("org-acme", "acme-user-values", "org-other"),
+			expectedError: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := clientgofake.NewClientset()
+			clientset.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				r := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				r.Status.Allowed = r.Spec.ResourceAttributes.Namespace == "org-acme" && r.Spec.User == "jane"
+				return true, r, nil
+			})
+
+			r, err := New(Config{
+				K8sClient: k8sclienttest.NewClients(k8sclienttest.ClientsConfig{K8sClient: clientset}),
+				Logger:    microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			err = r.Review(context.Background(), tc.app, tc.currentApp, authv1.UserInfo{Username: "jane"})
+			switch {
+			case err != nil && !tc.expectedError:
+				t.Fatalf("error == %#v, want nil", err)
+			case tc.expectedError && !IsAccessDenied(err):
+				t.Fatalf("error == %#v, want accessDeniedError", err)
+			}
+		})
+	}
+}
+
+func newApp(namespace, userSecret, kubeConfigNamespace string) v1alpha1.App {
+	return v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hello-world",
+			Namespace: namespace,
+		},
+		Spec: v1alpha1.AppSpec{
+			KubeConfig: v1alpha1.AppSpecKubeConfig{
+				Secret: v1alpha1.AppSpecKubeConfigSecret{
+					Name:      "cluster-kubeconfig",
+					Namespace: kubeConfigNamespace,
+				},
+			},
+			UserConfig: v1alpha1.AppSpecUserConfig{
+				Secret: v1alpha1.AppSpecUserConfigSecret{
+					Name:      userSecret,
+					Namespace: namespace,
+				},
+			},
+		},
+	}
+}
+
+func newAppPtr(namespace, userSecret, kubeConfigNamespace string) *v1alpha1.App {
+	app := newApp(namespace, userSecret, kubeConfigNamespace)
+	return &app
+}
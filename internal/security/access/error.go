@@ -0,0 +1,23 @@
+package access
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var accessDeniedError = &microerror.Error{
+	Kind: "accessDeniedError",
+}
+
+// IsAccessDenied asserts accessDeniedError.
+func IsAccessDenied(err error) bool {
+	return microerror.Cause(err) == accessDeniedError
+}
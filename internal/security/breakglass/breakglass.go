@@ -0,0 +1,263 @@
+// Package breakglass verifies signed break-glass tokens, which let App CRs
+// bypass configured rules during incidents without changing the
+// configuration of the admission controller or disabling its webhooks.
+package breakglass
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/metrics"
+)
+
+const (
+	// minHMACKeyLength is the minimum length of HMAC keys in bytes, the
+	// size of the SHA-256 hash.
+	minHMACKeyLength = 32
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+
+	// SecretName and SecretNamespace name the Secret holding the keys
+	// tokens are verified with, by their IDs. Each entry holds either an
+	// ed25519 public key in PEM format, or an HMAC-SHA256 key.
+	SecretName      string
+	SecretNamespace string
+
+	// Rules are the IDs of the rules tokens bypass, or their classes, i.e.
+	// the prefixes of their IDs, e.g. policy bypasses all policies.
+	Rules []string
+	// Silent disables the bypass metrics and logs, for evaluations which
+	// are no admissions, e.g. of candidates.
+	Silent bool
+}
+
+// Verifier verifies the break-glass tokens of App CRs, and bypasses the
+// violations of the configured rules for valid ones.
+type Verifier struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+
+	secretName      string
+	secretNamespace string
+
+	rules  []string
+	silent bool
+}
+
+func New(config Config) (*Verifier, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.SecretName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretName must not be empty", config)
+	}
+	if config.SecretNamespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretNamespace must not be empty", config)
+	}
+	if len(config.Rules) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Rules must not be empty", config)
+	}
+
+	v := &Verifier{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		secretName:      config.SecretName,
+		secretNamespace: config.SecretNamespace,
+
+		rules:  config.Rules,
+		silent: config.Silent,
+	}
+
+	return v, nil
+}
+
+// Verify returns the break-glass token of the App CR, or nil when it has
+// none. It returns an invalidTokenError when the token is malformed, not
+// signed with a key of the Secret, issued for another App CR or expired.
+// Expired tokens left unchanged on updates are ignored instead, so that App
+// CRs can still be updated once the incident is over.
+func (v *Verifier) Verify(ctx context.Context, app v1alpha1.App, currentApp *v1alpha1.App) (*Token, error) {
+	encoded, ok := app.Annotations[Annotation]
+	if !ok {
+		return nil, nil
+	}
+
+	token, err := v.verify(ctx, encoded)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if token.App != app.Name || token.Namespace != app.Namespace {
+		return nil, microerror.Maskf(invalidTokenError, "break-glass token is issued for app %#q in namespace %#q", token.App, token.Namespace)
+	}
+
+	if !time.Now().Before(token.Expires) {
+		if currentApp != nil && currentApp.Annotations[Annotation] == encoded {
+			v.logger.Debugf(ctx, "ignoring unchanged break-glass token of app %#q in namespace %#q expired at %s", app.Name, app.Namespace, token.Expires.Format(time.RFC3339))
+			return nil, nil
+		}
+
+		return nil, microerror.Maskf(invalidTokenError, "break-glass token expired at %s", token.Expires.Format(time.RFC3339))
+	}
+
+	return &token, nil
+}
+
+// Bypass returns the IDs of the rules violated by the error which the token
+// bypasses, and the remaining violations, which is nil when all of them are
+// bypassed. Errors which are no violation of a rule are never bypassed.
+func (v *Verifier) Bypass(ctx context.Context, app v1alpha1.App, token *Token, err error) ([]string, error) {
+	if err == nil || token == nil {
+		return nil, err
+	}
+
+	var bypassed []string
+	var remaining []error
+	for _, violation := range enforcement.Violations(err) {
+		rule := enforcement.Rule(violation)
+		if !v.bypasses(rule) {
+			remaining = append(remaining, violation)
+			continue
+		}
+
+		bypassed = append(bypassed, rule)
+		if v.silent {
+			continue
+		}
+
+		metrics.BreakGlassBypasses.WithLabelValues(rule).Inc()
+
+		v.logger.LogCtx(ctx,
+			"level", "warning",
+			"message", "bypassing rule with break-glass token",
+			"rule", rule,
+			"app", app.Name,
+			"namespace", app.Namespace,
+			"key", token.Key,
+			"reason", token.Reason,
+			"violation", violation.Error(),
+		)
+	}
+
+	switch {
+	case len(bypassed) == 0:
+		return nil, err
+	case len(remaining) == 0:
+		return bypassed, nil
+	case len(remaining) == 1:
+		return bypassed, remaining[0]
+	default:
+		return bypassed, errors.Join(remaining...)
+	}
+}
+
+func (v *Verifier) bypasses(rule string) bool {
+	if rule == "" {
+		return false
+	}
+
+	for _, r := range v.rules {
+		if rule == r || strings.HasPrefix(rule, r+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verify decodes the token and verifies its signature with the key it names.
+func (v *Verifier) verify(ctx context.Context, encoded string) (Token, error) {
+	payload, signature, ok := strings.Cut(encoded, ".")
+	if !ok {
+		return Token{}, microerror.Maskf(invalidTokenError, "break-glass token must be formatted as <payload>.<signature>")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return Token{}, microerror.Maskf(invalidTokenError, "unable to decode payload of break-glass token: %s", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return Token{}, microerror.Maskf(invalidTokenError, "unable to decode signature of break-glass token: %s", err)
+	}
+
+	var token Token
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return Token{}, microerror.Maskf(invalidTokenError, "unable to parse payload of break-glass token: %s", err)
+	}
+
+	secret, err := v.k8sClient.K8sClient().CoreV1().Secrets(v.secretNamespace).Get(ctx, v.secretName, metav1.GetOptions{})
+	if err != nil {
+		return Token{}, microerror.Mask(err)
+	}
+
+	key, ok := secret.Data[token.Key]
+	if !ok {
+		return Token{}, microerror.Maskf(invalidTokenError, "break-glass token is signed with unknown key %#q", token.Key)
+	}
+
+	valid, err := verifySignature(key, []byte(payload), sig)
+	if err != nil {
+		return Token{}, microerror.Maskf(invalidConfigError, "key %#q of Secret %#q in namespace %#q: %s", token.Key, v.secretName, v.secretNamespace, err)
+	}
+	if !valid {
+		return Token{}, microerror.Maskf(invalidTokenError, "signature of break-glass token is invalid for key %#q", token.Key)
+	}
+
+	return token, nil
+}
+
+// verifySignature verifies the signature of the payload with the key, an
+// ed25519 public key in PEM format or otherwise an HMAC-SHA256 key. The
+// type of the key is only determined by the Secret, so that tokens can't
+// choose the algorithm they are verified with.
+func verifySignature(key, payload, signature []byte) (bool, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		if len(key) < minHMACKeyLength {
+			return false, microerror.Maskf(invalidConfigError, "HMAC keys must be at least %d bytes long", minHMACKeyLength)
+		}
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write(payload)
+
+		return hmac.Equal(mac.Sum(nil), signature), nil
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	edKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return false, microerror.Maskf(invalidConfigError, "public keys must be ed25519 keys, got %T", publicKey)
+	}
+
+	return ed25519.Verify(edKey, payload, signature), nil
+}
@@ -0,0 +1,266 @@
+package breakglass
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+)
+
+var testError = &microerror.Error{
+	Kind: "testError",
+}
+
+var hmacKey = []byte("0123456789abcdef0123456789abcdef")
+
+func Test_Verifier_Verify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	valid := Token{Key: "hmac", App: "hello-world", Namespace: "org-acme", Expires: time.Now().Add(time.Hour), Reason: "INC-42"}
+
+	expired := valid
+	expired.Expires = time.Now().Add(-time.Hour)
+
+	otherApp := valid
+	otherApp.App = "kiam"
+
+	unknownKey := valid
+	unknownKey.Key = "unknown"
+
+	ed25519Token := valid
+	ed25519Token.Key = "ed25519"
+
+	tests := []struct {
+		name          string
+		token         string
+		currentToken  string
+		expectedToken bool
+		errorMatcher  func(error) bool
+	}{
+		{
+			name: "case 0: app without token",
+		},
+		{
+			name:          "case 1: valid HMAC token",
+			token:         signHMAC(t, valid, hmacKey),
+			expectedToken: true,
+		},
+		{
+			name:          "case 2: valid ed25519 token",
+			token:         signEd25519(t, ed25519Token, privateKey),
+			expectedToken: true,
+		},
+		{
+			name:         "case 3: expired token",
+			token:        signHMAC(t, expired, hmacKey),
+			errorMatcher: IsInvalidToken,
+		},
+		{
+			name:         "case 4: token of another app",
+			token:        signHMAC(t, otherApp, hmacKey),
+			errorMatcher: IsInvalidToken,
+		},
+		{
+			name:         "case 5: token signed with another key",
+			token:        signHMAC(t, valid, []byte("fedcba9876543210fedcba9876543210")),
+			errorMatcher: IsInvalidToken,
+		},
+		{
+			name:         "case 6: token signed with unknown key",
+			token:        signHMAC(t, unknownKey, hmacKey),
+			errorMatcher: IsInvalidToken,
+		},
+		{
+			name:         "case 7: HMAC token claiming an ed25519 key",
+			token:        signHMAC(t, ed25519Token, hmacKey),
+			errorMatcher: IsInvalidToken,
+		},
+		{
+			name:         "case 8: malformed token",
+			token:        "break-glass",
+			errorMatcher: IsInvalidToken,
+		},
+		{
+			name:         "case 9: unchanged expired token is ignored",
+			token:        signHMAC(t, expired, hmacKey),
+			currentToken: signHMAC(t, expired, hmacKey),
+		},
+	}
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		K8sClient: clientgofake.NewClientset(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "break-glass", Namespace: "giantswarm"},
+				Data: map[string][]byte{
+					"hmac":    hmacKey,
+					"ed25519": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+				},
+			},
+		),
+	})
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := New(Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+
+				SecretName:      "break-glass",
+				SecretNamespace: "giantswarm",
+				Rules:           []string{"policy"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			app := newApp(tc.token)
+
+			var currentApp *v1alpha1.App
+			if tc.currentToken != "" {
+				c := newApp(tc.currentToken)
+				currentApp = &c
+			}
+
+			token, err := v.Verify(context.Background(), app, currentApp)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if (token != nil) != tc.expectedToken {
+				t.Fatalf("token == %#v, want token %t", token, tc.expectedToken)
+			}
+		})
+	}
+}
+
+func Test_Verifier_Bypass(t *testing.T) {
+	policyViolation := enforcement.WithRule(enforcement.PolicyRule("tenant"), microerror.Maskf(testError, "policy"))
+	blacklisted := enforcement.WithRule(enforcement.RuleBlacklistedApp, microerror.Maskf(testError, "blacklisted"))
+	reference := enforcement.WithRule(enforcement.RuleBlacklistedReference, microerror.Maskf(testError, "reference"))
+
+	tests := []struct {
+		name             string
+		err              error
+		expectedBypassed []string
+		expectedRejected []string
+	}{
+		{
+			name: "case 0: no violation",
+		},
+		{
+			name:             "case 1: rule of a bypassed class",
+			err:              policyViolation,
+			expectedBypassed: []string{enforcement.PolicyRule("tenant")},
+		},
+		{
+			name:             "case 2: only bypassed rules are bypassed",
+			err:              microerror.Mask(errors.Join(blacklisted, reference)),
+			expectedBypassed: []string{enforcement.RuleBlacklistedApp},
+			expectedRejected: []string{enforcement.RuleBlacklistedReference},
+		},
+		{
+			name:             "case 3: errors of no rule are not bypassed",
+			err:              microerror.Maskf(testError, "parsing failed"),
+			expectedRejected: []string{""},
+		},
+	}
+
+	v, err := New(Config{
+		K8sClient: k8sclienttest.NewClients(k8sclienttest.ClientsConfig{}),
+		Logger:    microloggertest.New(),
+
+		SecretName:      "break-glass",
+		SecretNamespace: "giantswarm",
+		Rules:           []string{"policy", enforcement.RuleBlacklistedApp},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bypassed, err := v.Bypass(context.Background(), newApp(""), &Token{Key: "hmac", Reason: "INC-42"}, tc.err)
+			if !reflect.DeepEqual(bypassed, tc.expectedBypassed) {
+				t.Fatalf("bypassed == %v, want %v", bypassed, tc.expectedBypassed)
+			}
+
+			var rejected []string
+			if err != nil {
+				for _, violation := range enforcement.Violations(err) {
+					rejected = append(rejected, enforcement.Rule(violation))
+				}
+			}
+			if !reflect.DeepEqual(rejected, tc.expectedRejected) {
+				t.Fatalf("rejected == %v, want %v", rejected, tc.expectedRejected)
+			}
+		})
+	}
+}
+
+func newApp(token string) v1alpha1.App {
+	app := v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hello-world",
+			Namespace: "org-acme",
+		},
+	}
+	if token != "" {
+		app.Annotations = map[string]string{Annotation: token}
+	}
+
+	return app
+}
+
+func signHMAC(t *testing.T, token Token, key []byte) string {
+	payload := encodePayload(t, token)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signEd25519(t *testing.T, token Token, key ed25519.PrivateKey) string {
+	payload := encodePayload(t, token)
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(payload)))
+}
+
+func encodePayload(t *testing.T, token Token) string {
+	data, err := json.Marshal(token)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
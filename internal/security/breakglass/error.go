@@ -0,0 +1,23 @@
+package breakglass
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidTokenError = &microerror.Error{
+	Kind: "invalidTokenError",
+}
+
+// IsInvalidToken asserts invalidTokenError.
+func IsInvalidToken(err error) bool {
+	return microerror.Cause(err) == invalidTokenError
+}
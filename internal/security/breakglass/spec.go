@@ -0,0 +1,26 @@
+package breakglass
+
+import (
+	"time"
+)
+
+// Annotation holds the break-glass token of an App CR.
+const Annotation = "app-admission-controller.giantswarm.io/break-glass"
+
+// Token is the signed payload of a break-glass token. Tokens are encoded as
+// <payload>.<signature>, both base64url encoded without padding, where the
+// payload is JSON and the signature is computed over the encoded payload.
+type Token struct {
+	// Key is the ID of the key the token is signed with, i.e. the key of
+	// the entry holding it in the Secret.
+	Key string `json:"key"`
+
+	// App and Namespace name the App CR the token is valid for.
+	App       string `json:"app"`
+	Namespace string `json:"namespace"`
+
+	// Expires is the time the token is valid until.
+	Expires time.Time `json:"expires"`
+	// Reason explains the use of the token, e.g. an incident ID.
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,194 @@
+// Package deletion guards the deletion of critical App CRs, e.g. of
+// chart-operator or the cluster app, which tears down the apps or the
+// workload cluster they belong to.
+package deletion
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/app/v8/pkg/key"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	authv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/matcher"
+)
+
+const (
+	criticalAppDeletionTemplate = "app %#q in namespace %#q is critical, as its %s, deleting it requires the %#q annotation set to %#q beforehand"
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+
+	// Inspector decides which users are whitelisted, and so allowed to
+	// delete critical App CRs without confirmation.
+	Inspector *secins.Inspector
+
+	// Names and Catalogs are matcher rules, matching exactly by default.
+	// Names match the names of App CRs, and the names of their charts.
+	// Labels are label keys, matching App CRs with the label, or
+	// <key>=<value> pairs, matching App CRs with the label set to the
+	// value. App CRs matching any of them are critical.
+	Names    []string
+	Catalogs []string
+	Labels   []string
+}
+
+// Guard denies the deletion of critical App CRs which is not confirmed.
+type Guard struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+	inspector *secins.Inspector
+
+	names    matcher.List
+	catalogs matcher.List
+	labels   []label
+}
+
+type label struct {
+	key   string
+	value string
+}
+
+func New(config Config) (*Guard, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Inspector == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Inspector must not be empty", config)
+	}
+
+	if len(config.Names) == 0 && len(config.Catalogs) == 0 && len(config.Labels) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Names, %T.Catalogs or %T.Labels must not be empty", config, config, config)
+	}
+
+	names, err := matcher.ParseList(config.Names, matcher.Exact)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Names: %s", config, err)
+	}
+	catalogs, err := matcher.ParseList(config.Catalogs, matcher.Exact)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Catalogs: %s", config, err)
+	}
+
+	var labels []label
+	for _, l := range config.Labels {
+		k, v, _ := strings.Cut(l, "=")
+		if k == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Labels must only contain <key> or <key>=<value>, got %#q", config, l)
+		}
+		labels = append(labels, label{key: k, value: v})
+	}
+
+	g := &Guard{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+		inspector: config.Inspector,
+
+		names:    names,
+		catalogs: catalogs,
+		labels:   labels,
+	}
+
+	return g, nil
+}
+
+// Check returns a criticalAppDeletionError when the App CR being deleted is
+// critical, unless its deletion was confirmed with the annotation, the user
+// is whitelisted, or the Cluster CR the App CR belongs to is deleted itself
+// or gone already.
+func (g *Guard) Check(ctx context.Context, app v1alpha1.App, userInfo authv1.UserInfo) error {
+	reason := g.critical(app)
+	if reason == "" {
+		return nil
+	}
+
+	if app.Annotations[Annotation] == "true" {
+		g.logger.Debugf(ctx, "allowing confirmed deletion of critical app %#q in namespace %#q", app.Name, app.Namespace)
+		return nil
+	}
+
+	if g.inspector.IsWhitelisted(ctx, userInfo) {
+		g.logger.Debugf(ctx, "allowing deletion of critical app %#q in namespace %#q by whitelisted user %#q", app.Name, app.Namespace, userInfo.Username)
+		return nil
+	}
+
+	deleted, err := g.clusterDeleted(ctx, app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if deleted {
+		g.logger.Debugf(ctx, "allowing deletion of critical app %#q in namespace %#q of deleted cluster", app.Name, app.Namespace)
+		return nil
+	}
+
+	return microerror.Maskf(criticalAppDeletionError, criticalAppDeletionTemplate, app.Name, app.Namespace, reason, Annotation, "true")
+}
+
+// critical returns why the App CR is critical, or an empty string when it
+// is not.
+func (g *Guard) critical(app v1alpha1.App) string {
+	if m, ok := g.names.Match(app.Name); ok {
+		return fmt.Sprintf("name matches %s", m.Describe())
+	}
+	if m, ok := g.names.Match(key.AppName(app)); ok {
+		return fmt.Sprintf("chart %#q matches %s", key.AppName(app), m.Describe())
+	}
+	if m, ok := g.catalogs.Match(key.CatalogName(app)); ok {
+		return fmt.Sprintf("catalog %#q matches %s", key.CatalogName(app), m.Describe())
+	}
+	for _, l := range g.labels {
+		value, ok := app.Labels[l.key]
+		switch {
+		case ok && l.value == "":
+			return fmt.Sprintf("label %#q is set", l.key)
+		case ok && l.value == value:
+			return fmt.Sprintf("label %#q is set to %#q", l.key, l.value)
+		}
+	}
+
+	return ""
+}
+
+// clusterDeleted returns whether the Cluster CR the App CR belongs to, by
+// its owner reference or otherwise its cluster label, is being deleted. A
+// missing Cluster CR only counts as deleted when the App CR is owned by it,
+// as the garbage collector deletes owned App CRs once their owner is gone,
+// while cluster labels may name clusters in other namespaces.
+func (g *Guard) clusterDeleted(ctx context.Context, app v1alpha1.App) (bool, error) {
+	name := key.ClusterLabel(app)
+	var owned bool
+	for _, ref := range app.OwnerReferences {
+		if ref.Kind == "Cluster" && strings.HasPrefix(ref.APIVersion, capiv1beta1.GroupVersion.Group+"/") {
+			name = ref.Name
+			owned = true
+			break
+		}
+	}
+	if name == "" {
+		return false, nil
+	}
+
+	var cluster capiv1beta1.Cluster
+	err := g.k8sClient.CtrlClient().Get(ctx, client.ObjectKey{Name: name, Namespace: app.Namespace}, &cluster)
+	if apierrors.IsNotFound(err) {
+		return owned, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return !cluster.DeletionTimestamp.IsZero(), nil
+}
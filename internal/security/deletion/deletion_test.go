@@ -0,0 +1,161 @@
+package deletion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	authv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+func Test_Guard_Check(t *testing.T) {
+	newApp := func(name, catalog string, mutate ...func(*v1alpha1.App)) v1alpha1.App {
+		app := v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "org-acme",
+				Labels: map[string]string{
+					"giantswarm.io/cluster": "abc01",
+				},
+			},
+			Spec: v1alpha1.AppSpec{
+				Catalog: catalog,
+			},
+		}
+		for _, m := range mutate {
+			m(&app)
+		}
+		return app
+	}
+	ownedBy := func(cluster string) func(*v1alpha1.App) {
+		return func(app *v1alpha1.App) {
+			app.OwnerReferences = []metav1.OwnerReference{{APIVersion: capiv1beta1.GroupVersion.String(), Kind: "Cluster", Name: cluster}}
+		}
+	}
+
+	deleted := metav1.NewTime(time.Now())
+
+	tests := []struct {
+		name          string
+		app           v1alpha1.App
+		userInfo      authv1.UserInfo
+		clusters      []client.Object
+		expectedError bool
+	}{
+		{
+			name: "case 0: app which is not critical",
+			app:  newApp("hello-world", "giantswarm"),
+		},
+		{
+			name:          "case 1: critical app by name",
+			app:           newApp("chart-operator", "giantswarm"),
+			expectedError: true,
+		},
+		{
+			name:          "case 2: critical app by name pattern",
+			app:           newApp("cluster-abc01", "cluster"),
+			expectedError: true,
+		},
+		{
+			name:          "case 3: critical app by catalog",
+			app:           newApp("coredns", "default"),
+			expectedError: true,
+		},
+		{
+			name: "case 4: critical app by chart name",
+			app: newApp("abc01", "cluster", func(app *v1alpha1.App) {
+				app.Spec.Name = "cluster-aws"
+			}),
+			expectedError: true,
+		},
+		{
+			name: "case 5: critical app by label",
+			app: newApp("cert-manager", "giantswarm", func(app *v1alpha1.App) {
+				app.Labels["giantswarm.io/managed-by"] = "cluster"
+			}),
+			expectedError: true,
+		},
+		{
+			name: "case 6: confirmed deletion",
+			app: newApp("chart-operator", "giantswarm", func(app *v1alpha1.App) {
+				app.Annotations = map[string]string{Annotation: "true"}
+			}),
+		},
+		{
+			name:     "case 7: whitelisted user",
+			app:      newApp("chart-operator", "giantswarm"),
+			userInfo: authv1.UserInfo{Username: "jane", Groups: []string{"admins"}},
+		},
+		{
+			name: "case 8: cluster being deleted",
+			app:  newApp("chart-operator", "giantswarm"),
+			clusters: []client.Object{
+				&capiv1beta1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "abc01", Namespace: "org-acme", DeletionTimestamp: &deleted, Finalizers: []string{"cluster.cluster.x-k8s.io"}}},
+			},
+		},
+		{
+			name: "case 9: cluster not being deleted",
+			app:  newApp("chart-operator", "giantswarm"),
+			clusters: []client.Object{
+				&capiv1beta1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "abc01", Namespace: "org-acme"}},
+			},
+			expectedError: true,
+		},
+		{
+			name: "case 10: owning cluster gone",
+			app:  newApp("chart-operator", "giantswarm", ownedBy("abc01")),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = capiv1beta1.AddToScheme(scheme)
+
+			k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+				CtrlClient: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.clusters...).Build(),
+			})
+
+			inspector, err := secins.New(secins.Config{
+				Logger:         microloggertest.New(),
+				GroupWhitelist: []string{"admins"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			g, err := New(Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Inspector: inspector,
+
+				Names:    []string{"chart-operator", "prefix:cluster-"},
+				Catalogs: []string{"default"},
+				Labels:   []string{"giantswarm.io/managed-by=cluster"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			err = g.Check(context.Background(), tc.app, tc.userInfo)
+			switch {
+			case err != nil && !tc.expectedError:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.expectedError:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !IsCriticalAppDeletion(err):
+				t.Fatalf("error == %#v, want critical app deletion", err)
+			}
+		})
+	}
+}
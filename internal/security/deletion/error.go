@@ -0,0 +1,23 @@
+package deletion
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var criticalAppDeletionError = &microerror.Error{
+	Kind: "criticalAppDeletionError",
+}
+
+// IsCriticalAppDeletion asserts criticalAppDeletionError.
+func IsCriticalAppDeletion(err error) bool {
+	return microerror.Cause(err) == criticalAppDeletionError
+}
@@ -0,0 +1,7 @@
+package deletion
+
+const (
+	// Annotation confirms the deletion of a critical App CR when set to
+	// true on the App CR before it is deleted.
+	Annotation = "app-admission-controller.giantswarm.io/confirm-deletion"
+)
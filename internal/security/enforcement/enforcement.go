@@ -0,0 +1,184 @@
+// Package enforcement decides how violations of the admission rules are
+// handled. Each rule carries a stable ID and a mode, so that new rules can be
+// rolled out in the warn, audit or dry-run mode to measure their impact
+// before enforcing them.
+package enforcement
+
+import (
+	"context"
+	"errors"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	authv1 "k8s.io/api/authentication/v1"
+
+	"github.com/giantswarm/app-admission-controller/v2/pkg/metrics"
+)
+
+type Config struct {
+	Logger micrologger.Logger
+
+	// Modes maps rule IDs to the mode their violations are handled in,
+	// taking precedence over the default mode of the rule.
+	Modes map[string]Mode
+	// Override optionally sets the mode of all rules, e.g. to stop
+	// rejecting requests during incidents.
+	Override Mode
+	// Silent disables the decision metrics and logs, for evaluations which
+	// are no admissions, e.g. audit runs.
+	Silent bool
+}
+
+// Enforcer applies the modes of the rules to their violations.
+type Enforcer struct {
+	logger micrologger.Logger
+
+	modes    map[string]Mode
+	override Mode
+	silent   bool
+}
+
+func New(config Config) (*Enforcer, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	for rule, mode := range config.Modes {
+		if !valid(mode) {
+			return nil, microerror.Maskf(invalidConfigError, "mode %#q of rule %#q is not one of %v", mode, rule, Modes)
+		}
+	}
+	if config.Override != "" && !valid(config.Override) {
+		return nil, microerror.Maskf(invalidConfigError, "override mode %#q is not one of %v", config.Override, Modes)
+	}
+
+	e := &Enforcer{
+		logger: config.Logger,
+
+		modes:    config.Modes,
+		override: config.Override,
+		silent:   config.Silent,
+	}
+
+	return e, nil
+}
+
+// Decide applies the modes of the rules violated by the error, which may
+// join the violations of several rules. It returns the admission warnings
+// of rules in the warn mode, and the violations of rules in the enforce
+// mode, which is nil when the request is admitted. Errors which are no
+// violation of a rule are always returned.
+func (e *Enforcer) Decide(ctx context.Context, app v1alpha1.App, userInfo authv1.UserInfo, err error) ([]string, error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	var warnings []string
+	var enforced []error
+	for _, v := range Violations(err) {
+		rule := Rule(v)
+		if rule == "" {
+			enforced = append(enforced, v)
+			continue
+		}
+
+		mode := e.Mode(v)
+		if !e.silent {
+			metrics.RuleDecisions.WithLabelValues(rule, string(mode)).Inc()
+		}
+
+		switch mode {
+		case Enforce:
+			enforced = append(enforced, v)
+			continue
+		case Warn:
+			warnings = append(warnings, v.Error())
+		case DryRun:
+			continue
+		}
+
+		if e.silent {
+			continue
+		}
+
+		e.logger.LogCtx(ctx,
+			"level", "info",
+			"message", "admitting app violating rule",
+			"rule", rule,
+			"mode", string(mode),
+			"app", app.Name,
+			"namespace", app.Namespace,
+			"user", userInfo.Username,
+			"violation", v.Error(),
+		)
+	}
+
+	switch len(enforced) {
+	case 0:
+		return warnings, nil
+	case 1:
+		return warnings, enforced[0]
+	default:
+		return warnings, errors.Join(enforced...)
+	}
+}
+
+// Mode returns the mode the violation is handled in. The override takes
+// precedence over the configured mode of the rule, which takes precedence
+// over its default mode.
+func (e *Enforcer) Mode(err error) Mode {
+	var v *violation
+	if !errors.As(err, &v) {
+		return Enforce
+	}
+
+	if e.override != "" {
+		return e.override
+	}
+	if mode, ok := e.modes[v.rule]; ok {
+		return mode
+	}
+
+	return v.mode
+}
+
+// ParseMode returns the mode with the name.
+func ParseMode(s string) (Mode, error) {
+	mode := Mode(s)
+	if !valid(mode) {
+		return "", microerror.Maskf(invalidConfigError, "mode %#q is not one of %v", s, Modes)
+	}
+
+	return mode, nil
+}
+
+func valid(mode Mode) bool {
+	for _, m := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Violations splits joined errors, which may be masked, into the single
+// violations.
+func Violations(err error) []error {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if _, ok := e.(*violation); ok {
+			break
+		}
+
+		if joined, ok := e.(interface{ Unwrap() []error }); ok {
+			var all []error
+			for _, j := range joined.Unwrap() {
+				all = append(all, Violations(j)...)
+			}
+			return all
+		}
+	}
+
+	return []error{err}
+}
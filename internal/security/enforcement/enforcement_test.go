@@ -0,0 +1,117 @@
+package enforcement
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger/microloggertest"
+	authv1 "k8s.io/api/authentication/v1"
+)
+
+var testError = &microerror.Error{
+	Kind: "testError",
+}
+
+func Test_Enforcer_Decide(t *testing.T) {
+	blacklisted := WithRule(RuleBlacklistedApp, microerror.Maskf(testError, "blacklisted"))
+	reference := WithRule(RuleBlacklistedReference, microerror.Maskf(testError, "reference"))
+	audited := WithRuleMode(ExpressionRule("audited"), Audit, microerror.Maskf(testError, "audited"))
+
+	tests := []struct {
+		name             string
+		modes            map[string]Mode
+		override         Mode
+		err              error
+		expectedWarnings []string
+		expectedRejected []string
+	}{
+		{
+			name: "case 0: no violation",
+		},
+		{
+			name:             "case 1: rules are enforced by default",
+			err:              blacklisted,
+			expectedRejected: []string{RuleBlacklistedApp},
+		},
+		{
+			name:             "case 2: configured warn mode admits with warning",
+			modes:            map[string]Mode{RuleBlacklistedApp: Warn},
+			err:              blacklisted,
+			expectedWarnings: []string{"test error: blacklisted"},
+		},
+		{
+			name: "case 3: default mode of the rule admits",
+			err:  audited,
+		},
+		{
+			name:             "case 4: configured mode takes precedence over the default",
+			modes:            map[string]Mode{ExpressionRule("audited"): Enforce},
+			err:              audited,
+			expectedRejected: []string{ExpressionRule("audited")},
+		},
+		{
+			name:             "case 5: joined violations are decided separately",
+			modes:            map[string]Mode{RuleBlacklistedApp: DryRun},
+			err:              microerror.Mask(errors.Join(blacklisted, reference)),
+			expectedRejected: []string{RuleBlacklistedReference},
+		},
+		{
+			name:     "case 6: override takes precedence",
+			modes:    map[string]Mode{RuleBlacklistedApp: Enforce},
+			override: Audit,
+			err:      microerror.Mask(errors.Join(blacklisted, reference)),
+		},
+		{
+			name:             "case 7: errors of no rule are always returned",
+			override:         DryRun,
+			err:              microerror.Maskf(testError, "parsing failed"),
+			expectedRejected: []string{""},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := New(Config{
+				Logger: microloggertest.New(),
+
+				Modes:    tc.modes,
+				Override: tc.override,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			warnings, err := e.Decide(context.Background(), v1alpha1.App{}, authv1.UserInfo{Username: "jane"}, tc.err)
+			if !reflect.DeepEqual(warnings, tc.expectedWarnings) {
+				t.Fatalf("warnings == %v, want %v", warnings, tc.expectedWarnings)
+			}
+
+			var rejected []string
+			if err != nil {
+				for _, v := range Violations(err) {
+					rejected = append(rejected, Rule(v))
+				}
+				if microerror.Cause(err) != testError {
+					t.Fatalf("cause == %#v, want %#v", microerror.Cause(err), testError)
+				}
+			}
+			if !reflect.DeepEqual(rejected, tc.expectedRejected) {
+				t.Fatalf("rejected == %v, want %v", rejected, tc.expectedRejected)
+			}
+		})
+	}
+}
+
+func Test_New(t *testing.T) {
+	_, err := New(Config{
+		Logger: microloggertest.New(),
+		Modes:  map[string]Mode{RuleBlacklistedApp: "sometimes"},
+	})
+	if !IsInvalidConfig(err) {
+		t.Fatalf("error == %#v, want invalid config", err)
+	}
+}
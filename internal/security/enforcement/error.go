@@ -0,0 +1,73 @@
+package enforcement
+
+import (
+	"errors"
+
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+// violation is the error of a rule, carrying its ID and default mode. It
+// unwraps to the original error, so that its cause can still be asserted.
+type violation struct {
+	rule string
+	mode Mode
+	err  error
+}
+
+func (v *violation) Error() string {
+	return v.err.Error()
+}
+
+func (v *violation) Unwrap() error {
+	return v.err
+}
+
+// WithRule marks the error as a violation of the rule, handled in the
+// enforce mode unless configured otherwise. It returns nil for nil errors.
+func WithRule(rule string, err error) error {
+	return WithRuleMode(rule, Enforce, err)
+}
+
+// WithViolation marks the error as a violation of the rule like WithRule,
+// when any of the matchers asserts it. Other errors, e.g. of the Kubernetes
+// API, are returned as is, so that they are rejected regardless of the
+// enforcement mode of the rule, rather than admitting apps during outages.
+func WithViolation(rule string, err error, matchers ...func(error) bool) error {
+	for _, isViolation := range matchers {
+		if isViolation(err) {
+			return WithRule(rule, err)
+		}
+	}
+
+	return err
+}
+
+// WithRuleMode marks the error as a violation of the rule, handled in the
+// given mode unless configured otherwise. It returns nil for nil errors.
+func WithRuleMode(rule string, mode Mode, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &violation{rule: rule, mode: mode, err: err}
+}
+
+// Rule returns the ID of the rule the error is a violation of, or an empty
+// string for errors of no rule.
+func Rule(err error) string {
+	var v *violation
+	if errors.As(err, &v) {
+		return v.rule
+	}
+
+	return ""
+}
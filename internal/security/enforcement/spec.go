@@ -0,0 +1,47 @@
+package enforcement
+
+// Mode is the way violations of a rule are handled.
+type Mode string
+
+const (
+	// Enforce rejects the request.
+	Enforce Mode = "enforce"
+	// Warn admits the request with an admission warning, and logs the
+	// violation.
+	Warn Mode = "warn"
+	// Audit admits the request, and logs the violation.
+	Audit Mode = "audit"
+	// DryRun admits the request, and only counts the violation in the
+	// metric.
+	DryRun Mode = "dry-run"
+)
+
+// Modes lists all modes.
+var Modes = []Mode{Enforce, Warn, Audit, DryRun}
+
+// IDs of the built-in rules. Policies and CEL rules are identified by their
+// names, see PolicyRule and ExpressionRule.
+const (
+	RuleBlacklistedApp       = "inspector/blacklisted-app"
+	RuleBlacklistedReference = "inspector/blacklisted-reference"
+	RulePrivilegedFields     = "inspector/privileged-fields"
+	RuleReferenceAccess      = "access/reference-access"
+	RuleClusterTargeting     = "targeting/cluster"
+	RuleOwnerTeam            = "ownership/owner-team"
+	RuleAppValidation        = "app/validation"
+	RuleAppUpdateValidation  = "app/update-validation"
+	RuleManagedFields        = "app/managed-fields"
+	RuleExtraConfigs         = "app/extra-configs"
+	RuleProtectedMetadata    = "app/protected-metadata"
+	RuleCriticalAppDeletion  = "deletion/critical-app"
+)
+
+// PolicyRule returns the ID of the policy with the name.
+func PolicyRule(name string) string {
+	return "policy/" + name
+}
+
+// ExpressionRule returns the ID of the CEL rule with the name.
+func ExpressionRule(name string) string {
+	return "rule/" + name
+}
@@ -2,17 +2,25 @@ package inspector
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"fmt"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 	"github.com/giantswarm/app/v8/pkg/key"
+	"github.com/giantswarm/k8smetadata/pkg/label"
 	"github.com/giantswarm/microerror"
 	authv1 "k8s.io/api/authentication/v1"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/matcher"
 )
 
 const (
-	appNotAllowedTemplate       = "installing %#q from %#q catalog is not allowed"
-	referenceNotAllowedTemplate = "references to %#q namespace not allowed"
+	uniqueAppCRVersion = "0.0.0"
+
+	appNotAllowedTemplate             = "installing %#q from %#q catalog is not allowed"
+	privilegedFieldNotAllowedTemplate = "user %#q is not allowed to change %s from %#q to %#q, as it determines whether the app is installed in the management cluster"
+	referenceNotAllowedTemplate       = "references to %#q namespace not allowed"
 )
 
 // Inspect check App CR against two things at the moment:
@@ -35,23 +43,87 @@ func (i *Inspector) Inspect(ctx context.Context, app v1alpha1.App, userInfo auth
 		return nil
 	}
 
-	// Validate app against blacklisted apps
-	// and catalogs
+	// Validate app against blacklisted apps and catalogs, and against
+	// references to blacklisted namespaces. Both are checked, as their
+	// rules may be in different enforcement modes.
+	var violations []error
+
 	err = i.isBlacklistedApp(ctx, app)
 	if err != nil {
-		i.logger.Errorf(ctx, err, "rejecting blacklisted %#q app", app.Name)
-		return microerror.Mask(err)
+		i.logger.Errorf(ctx, err, "found blacklisted %#q app", app.Name)
+		violations = append(violations, enforcement.WithRule(enforcement.RuleBlacklistedApp, err))
 	}
 
-	// Validate app against references to
-	// blacklisted namespaces
 	err = i.hasBlacklistedReference(ctx, app)
 	if err != nil {
-		i.logger.Errorf(ctx, err, "rejecting %#q app in %#q namespace due to blacklisted references", app.Name, app.Namespace)
-		return microerror.Mask(err)
+		i.logger.Errorf(ctx, err, "found blacklisted references of %#q app in %#q namespace", app.Name, app.Namespace)
+		violations = append(violations, enforcement.WithRule(enforcement.RuleBlacklistedReference, err))
 	}
 
-	return nil
+	switch len(violations) {
+	case 0:
+		return nil
+	case 1:
+		return microerror.Mask(violations[0])
+	default:
+		return microerror.Mask(errors.Join(violations...))
+	}
+}
+
+// IsWhitelisted checks if request comes from a whitelisted user or a
+// member of a whitelisted group.
+func (i *Inspector) IsWhitelisted(ctx context.Context, userInfo authv1.UserInfo) bool {
+	return i.isWhitelistedActor(ctx, userInfo)
+}
+
+// InspectPrivilegedFields checks if the user is allowed to set or change the
+// fields making the App CR reconciled by the management cluster app-operator
+// and so installed in the management cluster itself:
+// - .spec.kubeConfig.inCluster
+// - the unique app-operator.giantswarm.io/version label
+//
+// The current App CR is nil on create. Changes are only allowed for
+// whitelisted and privileged actors, when restricting them is enabled.
+func (i *Inspector) InspectPrivilegedFields(ctx context.Context, app v1alpha1.App, currentApp *v1alpha1.App, userInfo authv1.UserInfo) error {
+	if !i.current().restrictPrivilegedFields {
+		return nil
+	}
+
+	var current v1alpha1.App
+	if currentApp != nil {
+		current = *currentApp
+	}
+
+	field, from, to := changedPrivilegedField(app, current)
+	if field == "" {
+		return nil
+	}
+
+	if i.isWhitelistedActor(ctx, userInfo) || i.isPrivilegedActor(userInfo) {
+		i.logger.Debugf(ctx, "allowing change of %s of app %#q in namespace %#q by authorised user %#q", field, app.Name, app.Namespace, userInfo.Username)
+		return nil
+	}
+
+	err := microerror.Maskf(securityViolationError, privilegedFieldNotAllowedTemplate, userInfo.Username, field, from, to)
+	i.logger.Errorf(ctx, err, "found change of privileged field of %#q app in %#q namespace", app.Name, app.Namespace)
+
+	return enforcement.WithRule(enforcement.RulePrivilegedFields, err)
+}
+
+// changedPrivilegedField returns the first privileged field differing
+// between the App CRs, with its current and new values. Setting the version
+// label is only privileged when the unique version is involved.
+func changedPrivilegedField(app, currentApp v1alpha1.App) (string, string, string) {
+	if key.InCluster(app) != key.InCluster(currentApp) {
+		return "`.spec.kubeConfig.inCluster`", fmt.Sprint(key.InCluster(currentApp)), fmt.Sprint(key.InCluster(app))
+	}
+
+	from, to := key.VersionLabel(currentApp), key.VersionLabel(app)
+	if from != to && (from == uniqueAppCRVersion || to == uniqueAppCRVersion) {
+		return fmt.Sprintf("the %#q label", label.AppOperatorVersion), from, to
+	}
+
+	return "", "", ""
 }
 
 // hasBlacklistedReference checks if application references configuration
@@ -70,20 +142,14 @@ func (i *Inspector) hasBlacklistedReference(ctx context.Context, app v1alpha1.Ap
 		referencedNamespaces = append(referencedNamespaces, extraConfig.Namespace)
 	}
 
+	namespaceBlacklist := i.current().namespaceBlacklist
+
 	for _, ns := range referencedNamespaces {
-		if _, ok := i.fixedNamespaceBlacklist[ns]; ok {
+		if namespaceBlacklist.Matches(ns) {
 			return microerror.Maskf(securityViolationError, referenceNotAllowedTemplate, ns)
 		}
 	}
 
-	for _, rns := range referencedNamespaces {
-		for _, pns := range i.dynamicNamespaceBlacklist {
-			if strings.HasSuffix(rns, pns) || strings.HasPrefix(rns, pns) {
-				return microerror.Maskf(securityViolationError, referenceNotAllowedTemplate, rns)
-			}
-		}
-	}
-
 	return nil
 }
 
@@ -91,8 +157,10 @@ func (i *Inspector) hasBlacklistedReference(ctx context.Context, app v1alpha1.Ap
 // from one of prohibited catalogs, see:
 // https://github.com/giantswarm/giantswarm/issues/21953
 func (i *Inspector) isBlacklistedApp(ctx context.Context, app v1alpha1.App) error {
-	_, isAppBlacklisted := i.appBlacklist[key.AppName(app)]
-	_, isAppCatalogBlacklisted := i.catalogBlacklist[key.CatalogName(app)]
+	r := i.current()
+
+	isAppBlacklisted := r.appBlacklist.Matches(key.AppName(app))
+	isAppCatalogBlacklisted := r.catalogBlacklist.Matches(key.CatalogName(app))
 
 	if isAppBlacklisted && isAppCatalogBlacklisted {
 		return microerror.Maskf(securityViolationError, appNotAllowedTemplate, key.AppName(app), key.CatalogName(app))
@@ -103,10 +171,16 @@ func (i *Inspector) isBlacklistedApp(ctx context.Context, app v1alpha1.App) erro
 
 // isPrivateApp checks if app comes from one of protected namespaces.
 // If yes, it means app is being installed by Giantswarm stuff or
-// Giantswarm controllers.
+// Giantswarm controllers. Only namespaces blacklisted exactly are
+// considered, as patterns may match namespaces of customers.
 func (i *Inspector) isPrivateApp(ctx context.Context, app v1alpha1.App) bool {
-	_, ok := i.fixedNamespaceBlacklist[app.Namespace]
-	return ok
+	for _, m := range i.current().namespaceBlacklist {
+		if m.Kind() == matcher.Exact && m.Matches(app.Namespace) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // isWhitelistedActor checks if request comes from a whitelisted user.
@@ -128,17 +202,28 @@ func (i *Inspector) isWhitelistedActor(ctx context.Context, userInfo authv1.User
 	return false
 }
 
-func (i *Inspector) isWhitelistedGroup(name string) bool {
-	_, ok := i.groupWhitelist[name]
-	return ok
-}
+// isPrivilegedActor checks if request comes from a user or a member of a
+// group allowed to change privileged fields.
+func (i *Inspector) isPrivilegedActor(userInfo authv1.UserInfo) bool {
+	r := i.current()
 
-func (i *Inspector) isWhitelistedUser(name string) bool {
-	for _, user := range i.userWhitelist {
-		if strings.HasPrefix(name, user) {
+	if r.privilegedUsers.Matches(userInfo.Username) {
+		return true
+	}
+
+	for _, group := range userInfo.Groups {
+		if r.privilegedGroups.Matches(group) {
 			return true
 		}
 	}
 
 	return false
 }
+
+func (i *Inspector) isWhitelistedGroup(name string) bool {
+	return i.current().groupWhitelist.Matches(name)
+}
+
+func (i *Inspector) isWhitelistedUser(name string) bool {
+	return i.current().userWhitelist.Matches(name)
+}
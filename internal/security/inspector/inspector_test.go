@@ -6,6 +6,8 @@ import (
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 	"github.com/giantswarm/micrologger/microloggertest"
+	authv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/errors"
 )
 
@@ -122,6 +124,50 @@ func Test_Inspector_hasBlacklistedReference(t *testing.T) {
 			},
 			errorMatcher: IsSecurityViolationError,
 		},
+		{
+			name:               "test case 5: legacy hyphen rule matches by prefix or suffix",
+			namespaceBlacklist: []string{"capi-"},
+			app: v1alpha1.App{
+				Spec: v1alpha1.AppSpec{
+					UserConfig: v1alpha1.AppSpecUserConfig{
+						Secret: v1alpha1.AppSpecUserConfigSecret{
+							Name:      "test",
+							Namespace: "team-capi-",
+						},
+					},
+				},
+			},
+			errorMatcher: IsSecurityViolationError,
+		},
+		{
+			name:               "test case 6: prefix rule only matches by prefix",
+			namespaceBlacklist: []string{"prefix:capi-"},
+			app: v1alpha1.App{
+				Spec: v1alpha1.AppSpec{
+					UserConfig: v1alpha1.AppSpecUserConfig{
+						Secret: v1alpha1.AppSpecUserConfigSecret{
+							Name:      "test",
+							Namespace: "team-capi-",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:               "test case 7: regex rule matches whole namespace",
+			namespaceBlacklist: []string{"regex:org-(giantswarm|admin)"},
+			app: v1alpha1.App{
+				Spec: v1alpha1.AppSpec{
+					UserConfig: v1alpha1.AppSpecUserConfig{
+						Secret: v1alpha1.AppSpecUserConfigSecret{
+							Name:      "test",
+							Namespace: "org-admin",
+						},
+					},
+				},
+			},
+			errorMatcher: IsSecurityViolationError,
+		},
 	}
 
 	for _, tc := range tests {
@@ -153,3 +199,144 @@ func Test_Inspector_hasBlacklistedReference(t *testing.T) {
 		})
 	}
 }
+
+func Test_Inspector_InspectPrivilegedFields(t *testing.T) {
+	inCluster := func(app v1alpha1.App) v1alpha1.App {
+		app.Spec.KubeConfig.InCluster = true
+		return app
+	}
+	versionLabel := func(app v1alpha1.App, version string) v1alpha1.App {
+		app.Labels = map[string]string{"app-operator.giantswarm.io/version": version}
+		return app
+	}
+	current := func(app v1alpha1.App) *v1alpha1.App {
+		return &app
+	}
+
+	app := v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hello-world",
+			Namespace: "org-acme",
+		},
+	}
+
+	tests := []struct {
+		name         string
+		restrict     bool
+		app          v1alpha1.App
+		currentApp   *v1alpha1.App
+		userInfo     authv1.UserInfo
+		errorMatcher errors.Matcher
+	}{
+		{
+			name:     "case 0: create of regular app is allowed",
+			restrict: true,
+			app:      versionLabel(app, "6.0.0"),
+			userInfo: authv1.UserInfo{Username: "jane"},
+		},
+		{
+			name:         "case 1: create of in-cluster app is rejected",
+			restrict:     true,
+			app:          inCluster(app),
+			userInfo:     authv1.UserInfo{Username: "jane"},
+			errorMatcher: IsSecurityViolationError,
+		},
+		{
+			name:         "case 2: create of app with unique version label is rejected",
+			restrict:     true,
+			app:          versionLabel(app, "0.0.0"),
+			userInfo:     authv1.UserInfo{Username: "jane"},
+			errorMatcher: IsSecurityViolationError,
+		},
+		{
+			name:     "case 3: create of in-cluster app is allowed when not restricted",
+			restrict: false,
+			app:      inCluster(app),
+			userInfo: authv1.UserInfo{Username: "jane"},
+		},
+		{
+			name:     "case 4: create of in-cluster app by whitelisted user is allowed",
+			restrict: true,
+			app:      inCluster(app),
+			userInfo: authv1.UserInfo{Username: "system:serviceaccount:giantswarm:app-operator"},
+		},
+		{
+			name:     "case 5: create of in-cluster app by privileged group member is allowed",
+			restrict: true,
+			app:      inCluster(app),
+			userInfo: authv1.UserInfo{Username: "jane", Groups: []string{"platform-admins"}},
+		},
+		{
+			name:     "case 6: create of in-cluster app by privileged user is allowed",
+			restrict: true,
+			app:      inCluster(app),
+			userInfo: authv1.UserInfo{Username: "ops:john"},
+		},
+		{
+			name:       "case 7: update of unchanged in-cluster app is allowed",
+			restrict:   true,
+			app:        inCluster(versionLabel(app, "0.0.0")),
+			currentApp: current(inCluster(versionLabel(app, "0.0.0"))),
+			userInfo:   authv1.UserInfo{Username: "jane"},
+		},
+		{
+			name:         "case 8: update setting in-cluster is rejected",
+			restrict:     true,
+			app:          inCluster(app),
+			currentApp:   current(app),
+			userInfo:     authv1.UserInfo{Username: "jane"},
+			errorMatcher: IsSecurityViolationError,
+		},
+		{
+			name:         "case 9: update unsetting in-cluster is rejected",
+			restrict:     true,
+			app:          app,
+			currentApp:   current(inCluster(app)),
+			userInfo:     authv1.UserInfo{Username: "jane"},
+			errorMatcher: IsSecurityViolationError,
+		},
+		{
+			name:         "case 10: update changing the unique version label is rejected",
+			restrict:     true,
+			app:          versionLabel(app, "6.0.0"),
+			currentApp:   current(versionLabel(app, "0.0.0")),
+			userInfo:     authv1.UserInfo{Username: "jane"},
+			errorMatcher: IsSecurityViolationError,
+		},
+		{
+			name:       "case 11: update changing a regular version label is allowed",
+			restrict:   true,
+			app:        versionLabel(app, "6.1.0"),
+			currentApp: current(versionLabel(app, "6.0.0")),
+			userInfo:   authv1.UserInfo{Username: "jane"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			inspector, err := New(Config{
+				Logger:        microloggertest.New(),
+				UserWhitelist: []string{"system:serviceaccount:giantswarm:"},
+
+				RestrictPrivilegedFields: tc.restrict,
+				PrivilegedGroups:         []string{"platform-admins"},
+				PrivilegedUsers:          []string{"ops:"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			err = inspector.InspectPrivilegedFields(ctx, tc.app, tc.currentApp, tc.userInfo)
+
+			if tc.errorMatcher == nil {
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			} else if !tc.errorMatcher(err) {
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
@@ -1,33 +1,60 @@
 package inspector
 
 import (
+	"context"
 	"strings"
+	"sync"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
-)
 
-type empty struct{}
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/matcher"
+)
 
 type Config struct {
 	Logger micrologger.Logger
 
+	// The lists hold matcher rules, see the matcher package. Rules without
+	// an explicit kind keep their legacy meaning: user rules match by
+	// prefix, namespace rules starting or ending with a hyphen match by
+	// prefix or suffix, and all others match exactly.
 	AppBlacklist       []string
 	CatalogBlacklist   []string
 	GroupWhitelist     []string
 	NamespaceBlacklist []string
 	UserWhitelist      []string
+
+	// RestrictPrivilegedFields enables the authorisation of changes to the
+	// fields making App CRs reconciled in the management cluster. They are
+	// allowed for whitelisted actors and the privileged ones below.
+	RestrictPrivilegedFields bool
+	PrivilegedGroups         []string
+	PrivilegedUsers          []string
 }
 
 type Inspector struct {
 	logger micrologger.Logger
 
-	appBlacklist              map[string]empty
-	catalogBlacklist          map[string]empty
-	dynamicNamespaceBlacklist []string
-	fixedNamespaceBlacklist   map[string]empty
-	groupWhitelist            map[string]empty
-	userWhitelist             []string
+	// config is the configuration given to New, which configurations
+	// given to Update are merged into.
+	config Config
+
+	mu    sync.RWMutex
+	rules *rules
+}
+
+// rules holds the compiled configuration. It is replaced as a whole on
+// updates.
+type rules struct {
+	appBlacklist       matcher.List
+	catalogBlacklist   matcher.List
+	groupWhitelist     matcher.List
+	namespaceBlacklist matcher.List
+	userWhitelist      matcher.List
+
+	restrictPrivilegedFields bool
+	privilegedGroups         matcher.List
+	privilegedUsers          matcher.List
 }
 
 func New(config Config) (*Inspector, error) {
@@ -35,36 +62,136 @@ func New(config Config) (*Inspector, error) {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
 
-	inspector := Inspector{
+	r, err := compile(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	inspector := &Inspector{
 		logger: config.Logger,
+		config: config,
 
-		appBlacklist:              make(map[string]empty),
-		catalogBlacklist:          make(map[string]empty),
-		dynamicNamespaceBlacklist: make([]string, 0),
-		fixedNamespaceBlacklist:   make(map[string]empty),
-		groupWhitelist:            make(map[string]empty),
-		userWhitelist:             config.UserWhitelist,
+		rules: r,
 	}
 
-	for _, i := range config.NamespaceBlacklist {
-		if strings.HasPrefix(i, "-") || strings.HasSuffix(i, "-") {
-			inspector.dynamicNamespaceBlacklist = append(inspector.dynamicNamespaceBlacklist, i)
-		} else {
-			inspector.fixedNamespaceBlacklist[i] = empty{}
+	return inspector, nil
+}
+
+// Update replaces the rules of the inspector with the ones of the
+// configuration given to New merged with the given configurations, e.g.
+// of policy objects. Lists are concatenated, and privileged fields are
+// restricted when any configuration restricts them. The current rules are
+// kept when any of the configurations is invalid.
+func (i *Inspector) Update(configs ...Config) error {
+	merged := i.config
+	for _, c := range configs {
+		merged = merge(merged, c)
+	}
+
+	r, err := compile(merged)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	i.mu.Lock()
+	i.rules = r
+	i.mu.Unlock()
+
+	return nil
+}
+
+// Validate checks if the configuration compiles, without using it.
+func Validate(config Config) error {
+	_, err := compile(config)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (i *Inspector) current() *rules {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.rules
+}
+
+func compile(config Config) (*rules, error) {
+	r := &rules{
+		restrictPrivilegedFields: config.RestrictPrivilegedFields,
+	}
+
+	lists := []struct {
+		name   string
+		rules  []string
+		legacy func(rule string) matcher.Kind
+		list   *matcher.List
+	}{
+		{name: "blacklisted app", rules: config.AppBlacklist, legacy: exact, list: &r.appBlacklist},
+		{name: "blacklisted catalog", rules: config.CatalogBlacklist, legacy: exact, list: &r.catalogBlacklist},
+		{name: "whitelisted group", rules: config.GroupWhitelist, legacy: exact, list: &r.groupWhitelist},
+		{name: "blacklisted namespace", rules: config.NamespaceBlacklist, legacy: legacyNamespaceKind, list: &r.namespaceBlacklist},
+		{name: "whitelisted user", rules: config.UserWhitelist, legacy: prefix, list: &r.userWhitelist},
+		{name: "privileged group", rules: config.PrivilegedGroups, legacy: exact, list: &r.privilegedGroups},
+		{name: "privileged user", rules: config.PrivilegedUsers, legacy: prefix, list: &r.privilegedUsers},
+	}
+
+	ctx := context.Background()
+
+	for _, l := range lists {
+		for _, rule := range l.rules {
+			m, err := matcher.Parse(rule, l.legacy(rule))
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "%s: %s", l.name, err)
+			}
+
+			if config.Logger != nil {
+				config.Logger.Debugf(ctx, "%s rule %#q matches %s", l.name, rule, m.Describe())
+			}
+
+			*l.list = append(*l.list, m)
 		}
 	}
 
-	for _, i := range config.GroupWhitelist {
-		inspector.groupWhitelist[i] = empty{}
+	return r, nil
+}
+
+func merge(a, b Config) Config {
+	concat := func(a, b []string) []string {
+		return append(append([]string{}, a...), b...)
 	}
 
-	for _, i := range config.AppBlacklist {
-		inspector.appBlacklist[i] = empty{}
+	return Config{
+		Logger: a.Logger,
+
+		AppBlacklist:       concat(a.AppBlacklist, b.AppBlacklist),
+		CatalogBlacklist:   concat(a.CatalogBlacklist, b.CatalogBlacklist),
+		GroupWhitelist:     concat(a.GroupWhitelist, b.GroupWhitelist),
+		NamespaceBlacklist: concat(a.NamespaceBlacklist, b.NamespaceBlacklist),
+		UserWhitelist:      concat(a.UserWhitelist, b.UserWhitelist),
+
+		RestrictPrivilegedFields: a.RestrictPrivilegedFields || b.RestrictPrivilegedFields,
+		PrivilegedGroups:         concat(a.PrivilegedGroups, b.PrivilegedGroups),
+		PrivilegedUsers:          concat(a.PrivilegedUsers, b.PrivilegedUsers),
 	}
+}
+
+func exact(string) matcher.Kind {
+	return matcher.Exact
+}
+
+func prefix(string) matcher.Kind {
+	return matcher.Prefix
+}
 
-	for _, i := range config.CatalogBlacklist {
-		inspector.catalogBlacklist[i] = empty{}
+// legacyNamespaceKind keeps the meaning of namespace blacklist rules written
+// before matcher kinds existed, where rules starting or ending with a hyphen
+// matched namespaces starting or ending with them.
+func legacyNamespaceKind(rule string) matcher.Kind {
+	if strings.HasPrefix(rule, "-") || strings.HasSuffix(rule, "-") {
+		return matcher.PrefixOrSuffix
 	}
 
-	return &inspector, nil
+	return matcher.Exact
 }
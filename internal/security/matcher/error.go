@@ -0,0 +1,14 @@
+package matcher
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidPatternError = &microerror.Error{
+	Kind: "invalidPatternError",
+}
+
+// IsInvalidPattern asserts invalidPatternError.
+func IsInvalidPattern(err error) bool {
+	return microerror.Cause(err) == invalidPatternError
+}
@@ -0,0 +1,178 @@
+// Package matcher implements the rules matching names of namespaces, users,
+// groups, apps and catalogs in the security configuration.
+//
+// Rules are written as <kind>:<pattern>, e.g. prefix:capi- or
+// regex:org-[a-z]+. Rules without a known kind are interpreted with the
+// legacy kind of the list they are given in, so existing configuration keeps
+// its meaning.
+package matcher
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Kind is the way a rule matches names.
+type Kind string
+
+const (
+	// Exact matches names equal to the pattern.
+	Exact Kind = "exact"
+	// Prefix matches names starting with the pattern.
+	Prefix Kind = "prefix"
+	// Suffix matches names ending with the pattern.
+	Suffix Kind = "suffix"
+	// Glob matches names with shell glob patterns, see path.Match.
+	Glob Kind = "glob"
+	// Regex matches names with regular expressions anchored at both ends.
+	Regex Kind = "regex"
+
+	// PrefixOrSuffix matches names starting or ending with the pattern. It
+	// is only used to keep the meaning of legacy namespace blacklist
+	// entries starting or ending with a hyphen, and can not be written
+	// explicitly.
+	PrefixOrSuffix Kind = "prefix-or-suffix"
+)
+
+var kinds = []Kind{Exact, Prefix, Suffix, Glob, Regex}
+
+// Matcher is a parsed rule.
+type Matcher struct {
+	kind    Kind
+	pattern string
+	legacy  bool
+	regex   *regexp.Regexp
+}
+
+// Parse parses the rule. Rules without an explicit kind are given the
+// legacy kind.
+func Parse(rule string, legacy Kind) (Matcher, error) {
+	m := Matcher{
+		kind:    legacy,
+		pattern: rule,
+		legacy:  true,
+	}
+
+	for _, k := range kinds {
+		if p, ok := strings.CutPrefix(rule, string(k)+":"); ok {
+			m.kind = k
+			m.pattern = p
+			m.legacy = false
+			break
+		}
+	}
+
+	switch m.kind {
+	case Glob:
+		_, err := path.Match(m.pattern, "")
+		if err != nil {
+			return Matcher{}, microerror.Maskf(invalidPatternError, "rule %#q: %s", rule, err)
+		}
+	case Regex:
+		r, err := regexp.Compile("^(?:" + m.pattern + ")$")
+		if err != nil {
+			return Matcher{}, microerror.Maskf(invalidPatternError, "rule %#q: %s", rule, err)
+		}
+		m.regex = r
+	}
+
+	return m, nil
+}
+
+// Matches checks if the name matches the rule.
+func (m Matcher) Matches(name string) bool {
+	switch m.kind {
+	case Exact:
+		return name == m.pattern
+	case Prefix:
+		return strings.HasPrefix(name, m.pattern)
+	case Suffix:
+		return strings.HasSuffix(name, m.pattern)
+	case PrefixOrSuffix:
+		return strings.HasPrefix(name, m.pattern) || strings.HasSuffix(name, m.pattern)
+	case Glob:
+		ok, _ := path.Match(m.pattern, name)
+		return ok
+	case Regex:
+		return m.regex.MatchString(name)
+	}
+
+	return false
+}
+
+// Kind returns the effective kind of the rule.
+func (m Matcher) Kind() Kind {
+	return m.kind
+}
+
+// Describe explains the effective meaning of the rule.
+func (m Matcher) Describe() string {
+	var d string
+	switch m.kind {
+	case Exact:
+		d = fmt.Sprintf("names equal to %#q", m.pattern)
+	case Prefix:
+		d = fmt.Sprintf("names starting with %#q", m.pattern)
+	case Suffix:
+		d = fmt.Sprintf("names ending with %#q", m.pattern)
+	case PrefixOrSuffix:
+		d = fmt.Sprintf("names starting or ending with %#q", m.pattern)
+	case Glob:
+		d = fmt.Sprintf("names matching the glob pattern %#q", m.pattern)
+	case Regex:
+		d = fmt.Sprintf("names fully matching the regular expression %#q", m.pattern)
+	}
+
+	switch {
+	case m.kind == PrefixOrSuffix:
+		d += fmt.Sprintf(", write %#q or %#q to match one way only", "prefix:"+m.pattern, "suffix:"+m.pattern)
+	case m.legacy && m.kind != Exact:
+		d += fmt.Sprintf(", write %#q to match exactly", "exact:"+m.pattern)
+	}
+
+	return d
+}
+
+// String returns the rule with its explicit kind.
+func (m Matcher) String() string {
+	return string(m.kind) + ":" + m.pattern
+}
+
+// List is a list of rules matching names matching any of them.
+type List []Matcher
+
+// ParseList parses the rules, see Parse.
+func ParseList(rules []string, legacy Kind) (List, error) {
+	var l List
+	for _, rule := range rules {
+		m, err := Parse(rule, legacy)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		l = append(l, m)
+	}
+
+	return l, nil
+}
+
+// Matches checks if the name matches any of the rules.
+func (l List) Matches(name string) bool {
+	_, ok := l.Match(name)
+	return ok
+}
+
+// Match returns the first rule the name matches.
+func (l List) Match(name string) (Matcher, bool) {
+	for _, m := range l {
+		if m.Matches(name) {
+			return m, true
+		}
+	}
+
+	return Matcher{}, false
+}
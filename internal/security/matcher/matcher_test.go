@@ -0,0 +1,52 @@
+package matcher
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test_Matcher_Matches(t *testing.T) {
+	tests := []struct {
+		rule     string
+		legacy   Kind
+		name     string
+		expected bool
+	}{
+		{rule: "giantswarm", legacy: Exact, name: "giantswarm", expected: true},
+		{rule: "giantswarm", legacy: Exact, name: "giantswarm-x", expected: false},
+		{rule: "prefix:capi-", legacy: Exact, name: "capi-system", expected: true},
+		{rule: "prefix:capi-", legacy: Exact, name: "team-capi-", expected: false},
+		{rule: "suffix:-prometheus", legacy: Exact, name: "abc01-prometheus", expected: true},
+		{rule: "suffix:-prometheus", legacy: Exact, name: "-prometheus-x", expected: false},
+		{rule: "capi-", legacy: PrefixOrSuffix, name: "team-capi-", expected: true},
+		{rule: "glob:org-*", legacy: Exact, name: "org-acme", expected: true},
+		{rule: "glob:org-*", legacy: Exact, name: "acme", expected: false},
+		{rule: "regex:org-[a-z]+", legacy: Exact, name: "org-acme", expected: true},
+		{rule: "regex:org-[a-z]+", legacy: Exact, name: "x-org-acme", expected: false},
+		{rule: "regex:org-[a-z]+", legacy: Exact, name: "org-acme01", expected: false},
+		{rule: "system:serviceaccount:giantswarm:", legacy: Prefix, name: "system:serviceaccount:giantswarm:app-operator", expected: true},
+		{rule: "exact:system:serviceaccount:giantswarm:", legacy: Prefix, name: "system:serviceaccount:giantswarm:app-operator", expected: false},
+	}
+
+	for i, tc := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			m, err := Parse(tc.rule, tc.legacy)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if m.Matches(tc.name) != tc.expected {
+				t.Fatalf("%s.Matches(%#q) == %t, want %t", m, tc.name, !tc.expected, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_Parse_invalid(t *testing.T) {
+	for _, rule := range []string{"glob:[", "regex:("} {
+		_, err := Parse(rule, Exact)
+		if !IsInvalidPattern(err) {
+			t.Fatalf("error == %#v, want invalid pattern error for %#q", err, rule)
+		}
+	}
+}
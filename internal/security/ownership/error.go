@@ -0,0 +1,23 @@
+package ownership
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var notOwnerError = &microerror.Error{
+	Kind: "notOwnerError",
+}
+
+// IsNotOwner asserts notOwnerError.
+func IsNotOwner(err error) bool {
+	return microerror.Cause(err) == notOwnerError
+}
@@ -0,0 +1,103 @@
+// Package ownership restricts changes of App CRs owned by a team to the
+// members of the groups of the team.
+package ownership
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+// Annotation holds the team owning the App CR.
+const Annotation = "app-admission-controller.giantswarm.io/owner-team"
+
+const (
+	notOwnerTemplate    = "app %#q in namespace %#q is owned by team %#q, so its spec and owner team may only be changed by members of the groups %s or whitelisted users"
+	unknownTeamTemplate = "app %#q in namespace %#q is owned by team %#q without groups, so its spec and owner team may only be changed by whitelisted users"
+)
+
+type Config struct {
+	Logger micrologger.Logger
+
+	// Inspector decides which users are whitelisted, and so allowed to
+	// change App CRs of any team.
+	Inspector *secins.Inspector
+
+	// Teams maps the teams to the groups of their members.
+	Teams map[string][]string
+}
+
+// Checker checks that changes of App CRs owned by a team are made by its
+// members.
+type Checker struct {
+	logger    micrologger.Logger
+	inspector *secins.Inspector
+
+	teams map[string][]string
+}
+
+func New(config Config) (*Checker, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Inspector == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Inspector must not be empty", config)
+	}
+	if len(config.Teams) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Teams must not be empty", config)
+	}
+
+	c := &Checker{
+		logger:    config.Logger,
+		inspector: config.Inspector,
+
+		teams: config.Teams,
+	}
+
+	return c, nil
+}
+
+// Check returns a notOwnerError when an update changes the spec or the owner
+// team of an App CR owned by a team, and the user is neither a member of one
+// of the groups of the team nor whitelisted. The current App CR is nil on
+// create, when the owner team may be set by anyone.
+func (c *Checker) Check(ctx context.Context, app v1alpha1.App, currentApp *v1alpha1.App, userInfo authv1.UserInfo) error {
+	if currentApp == nil {
+		return nil
+	}
+
+	team := currentApp.Annotations[Annotation]
+	if team == "" {
+		return nil
+	}
+
+	if app.Annotations[Annotation] == team && equality.Semantic.DeepEqual(app.Spec, currentApp.Spec) {
+		return nil
+	}
+
+	groups, ok := c.teams[team]
+	for _, g := range userInfo.Groups {
+		if slices.Contains(groups, g) {
+			return nil
+		}
+	}
+
+	if c.inspector.IsWhitelisted(ctx, userInfo) {
+		c.logger.Debugf(ctx, "allowing change of app %#q in namespace %#q owned by team %#q by whitelisted user %#q", app.Name, app.Namespace, team, userInfo.Username)
+		return nil
+	}
+
+	if !ok {
+		return microerror.Maskf(notOwnerError, unknownTeamTemplate, app.Name, app.Namespace, team)
+	}
+
+	return microerror.Maskf(notOwnerError, notOwnerTemplate, app.Name, app.Namespace, team, strings.Join(groups, ", "))
+}
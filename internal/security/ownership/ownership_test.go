@@ -0,0 +1,141 @@
+package ownership
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	authv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/errors"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+func Test_Checker_Check(t *testing.T) {
+	newApp := func(team, version string) v1alpha1.App {
+		app := v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "org-acme",
+			},
+			Spec: v1alpha1.AppSpec{
+				Version: version,
+			},
+		}
+		if team != "" {
+			app.Annotations = map[string]string{Annotation: team}
+		}
+		return app
+	}
+	current := func(app v1alpha1.App) *v1alpha1.App {
+		return &app
+	}
+
+	tests := []struct {
+		name         string
+		app          v1alpha1.App
+		currentApp   *v1alpha1.App
+		userInfo     authv1.UserInfo
+		errorMatcher errors.Matcher
+	}{
+		{
+			name:     "case 0: owner team may be set on create",
+			app:      newApp("rocket", "1.0.0"),
+			userInfo: authv1.UserInfo{Username: "jane"},
+		},
+		{
+			name:       "case 1: app without owner team may be changed",
+			app:        newApp("", "1.1.0"),
+			currentApp: current(newApp("", "1.0.0")),
+			userInfo:   authv1.UserInfo{Username: "jane"},
+		},
+		{
+			name:       "case 2: owner team may be set on update",
+			app:        newApp("rocket", "1.0.0"),
+			currentApp: current(newApp("", "1.0.0")),
+			userInfo:   authv1.UserInfo{Username: "jane"},
+		},
+		{
+			name:       "case 3: update keeping the spec and owner team is allowed",
+			app:        newApp("rocket", "1.0.0"),
+			currentApp: current(newApp("rocket", "1.0.0")),
+			userInfo:   authv1.UserInfo{Username: "jane"},
+		},
+		{
+			name:         "case 4: spec change by non-member is rejected",
+			app:          newApp("rocket", "1.1.0"),
+			currentApp:   current(newApp("rocket", "1.0.0")),
+			userInfo:     authv1.UserInfo{Username: "jane", Groups: []string{"team-honeybadger"}},
+			errorMatcher: IsNotOwner,
+		},
+		{
+			name:       "case 5: spec change by member is allowed",
+			app:        newApp("rocket", "1.1.0"),
+			currentApp: current(newApp("rocket", "1.0.0")),
+			userInfo:   authv1.UserInfo{Username: "jane", Groups: []string{"team-rocket"}},
+		},
+		{
+			name:         "case 6: owner team change by non-member is rejected",
+			app:          newApp("honeybadger", "1.0.0"),
+			currentApp:   current(newApp("rocket", "1.0.0")),
+			userInfo:     authv1.UserInfo{Username: "jane", Groups: []string{"team-honeybadger"}},
+			errorMatcher: IsNotOwner,
+		},
+		{
+			name:         "case 7: owner team removal by non-member is rejected",
+			app:          newApp("", "1.0.0"),
+			currentApp:   current(newApp("rocket", "1.0.0")),
+			userInfo:     authv1.UserInfo{Username: "jane"},
+			errorMatcher: IsNotOwner,
+		},
+		{
+			name:       "case 8: spec change by whitelisted user is allowed",
+			app:        newApp("rocket", "1.1.0"),
+			currentApp: current(newApp("rocket", "1.0.0")),
+			userInfo:   authv1.UserInfo{Username: "jane", Groups: []string{"admins"}},
+		},
+		{
+			name:         "case 9: spec change of app owned by unknown team is rejected",
+			app:          newApp("unknown", "1.1.0"),
+			currentApp:   current(newApp("unknown", "1.0.0")),
+			userInfo:     authv1.UserInfo{Username: "jane", Groups: []string{"team-rocket"}},
+			errorMatcher: IsNotOwner,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inspector, err := secins.New(secins.Config{
+				Logger:         microloggertest.New(),
+				GroupWhitelist: []string{"admins"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			c, err := New(Config{
+				Logger:    microloggertest.New(),
+				Inspector: inspector,
+				Teams: map[string][]string{
+					"honeybadger": {"team-honeybadger"},
+					"rocket":      {"team-rocket", "rocket-oncall"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			err = c.Check(context.Background(), tc.app, tc.currentApp, tc.userInfo)
+
+			if tc.errorMatcher == nil {
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			} else if !tc.errorMatcher(err) {
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package policy
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var policyViolationError = &microerror.Error{
+	Kind: "policyViolationError",
+}
+
+// IsPolicyViolation asserts policyViolationError.
+func IsPolicyViolation(err error) bool {
+	return microerror.Cause(err) == policyViolationError
+}
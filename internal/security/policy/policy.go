@@ -0,0 +1,405 @@
+// Package policy enforces per tenant policies on the catalogs and apps App
+// CRs may use. Tenants are selected by namespace, e.g. the namespace of an
+// organization, or by namespace labels.
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/app/v8/pkg/key"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/matcher"
+)
+
+const (
+	appDeniedTemplate                 = "app %#q is denied in namespace %#q by policy %#q"
+	appNotAllowedTemplate             = "app %#q is not allowed in namespace %#q by policy %#q"
+	catalogNotAllowedTemplate         = "catalog %#q in namespace %#q is not allowed in namespace %#q by policy %#q"
+	namespaceConfigDeniedTemplate     = "%s %#q with value %#q in .spec.namespaceConfig is denied in namespace %#q by policy %#q"
+	skipCRDsDeniedTemplate            = ".spec.install.skipCRDs is denied in namespace %#q by policy %#q"
+	targetNamespaceDeniedTemplate     = "target namespace %#q is denied in namespace %#q by policy %#q"
+	targetNamespaceNotAllowedTemplate = "target namespace %#q is not allowed in namespace %#q by policy %#q"
+	timeoutExceededTemplate           = "%s of %s exceeds the maximum of %s in namespace %#q by policy %#q"
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+
+	Policies []Policy
+}
+
+// Evaluator evaluates App CRs against the policies of their namespace.
+type Evaluator struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+
+	// base holds the policies given to New, which policies given to Update
+	// are added to.
+	base []policy
+
+	mu       sync.RWMutex
+	policies []policy
+}
+
+// policy is a validated Policy with its parsed namespace selector and
+// matcher rules.
+type policy struct {
+	Policy
+
+	namespaces matcher.List
+	selector   labels.Selector
+	rules      map[string]matcher.Matcher
+}
+
+func New(config Config) (*Evaluator, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	policies, err := parseAll(config.Policies)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	e := &Evaluator{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		base:     policies,
+		policies: policies,
+	}
+
+	return e, nil
+}
+
+// Update replaces the policies of the evaluator with the ones given to New
+// and the given ones, e.g. of policy objects. The current policies are kept
+// when any of the given ones is invalid.
+func (e *Evaluator) Update(policies []Policy) error {
+	parsed, err := parseAll(policies)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	e.mu.Lock()
+	e.policies = append(append([]policy{}, e.base...), parsed...)
+	e.mu.Unlock()
+
+	return nil
+}
+
+// Validate checks if the policy is valid, without using it.
+func Validate(p Policy) error {
+	_, err := parse(p)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Load reads policies from the YAML file.
+func Load(file string) ([]Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var policies []Policy
+	err = yaml.UnmarshalStrict(data, &policies)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%s: %s", file, err)
+	}
+
+	return policies, nil
+}
+
+// Evaluate returns a policyViolationError when the App CR violates one of
+// the policies applying to its namespace. Violations of several policies
+// are joined, each marked with the ID of the policy.
+func (e *Evaluator) Evaluate(ctx context.Context, app v1alpha1.App) error {
+	policies, err := e.matching(ctx, app.Namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// All policies are evaluated, as they may be in different enforcement
+	// modes.
+	var violations []error
+	for _, p := range policies {
+		err = p.evaluate(app)
+		if err != nil {
+			e.logger.Errorf(ctx, err, "found violation of policy %#q by app %#q in namespace %#q", p.Name, app.Name, app.Namespace)
+			violations = append(violations, enforcement.WithRule(enforcement.PolicyRule(p.Name), err))
+		}
+	}
+
+	switch len(violations) {
+	case 0:
+		return nil
+	case 1:
+		return microerror.Mask(violations[0])
+	default:
+		return microerror.Mask(errors.Join(violations...))
+	}
+}
+
+// matching returns the policies applying to the namespace. Its labels are
+// only read when a policy selects namespaces by them.
+func (e *Evaluator) matching(ctx context.Context, namespace string) ([]policy, error) {
+	var result []policy
+	var nsLabels labels.Set
+
+	e.mu.RLock()
+	policies := e.policies
+	e.mu.RUnlock()
+
+	for _, p := range policies {
+		if p.namespaces.Matches(namespace) {
+			result = append(result, p)
+			continue
+		}
+		if p.selector == nil {
+			continue
+		}
+
+		if nsLabels == nil {
+			ns, err := e.k8sClient.K8sClient().CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			nsLabels = labels.Set(ns.Labels)
+		}
+
+		if p.selector.Matches(nsLabels) {
+			result = append(result, p)
+		}
+	}
+
+	return result, nil
+}
+
+func (p policy) evaluate(app v1alpha1.App) error {
+	appName := key.AppName(app)
+
+	if p.denies(p.Apps, appName) {
+		return microerror.Maskf(policyViolationError, appDeniedTemplate, appName, app.Namespace, p.Name)
+	}
+	if !p.allows(p.Apps, appName) {
+		return microerror.Maskf(policyViolationError, appNotAllowedTemplate, appName, app.Namespace, p.Name)
+	}
+
+	err := p.evaluateCatalog(app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	targetNamespace := key.Namespace(app)
+
+	if p.denies(p.TargetNamespaces, targetNamespace) {
+		return microerror.Maskf(policyViolationError, targetNamespaceDeniedTemplate, targetNamespace, app.Namespace, p.Name)
+	}
+	if !p.allows(p.TargetNamespaces, targetNamespace) {
+		return microerror.Maskf(policyViolationError, targetNamespaceNotAllowedTemplate, targetNamespace, app.Namespace, p.Name)
+	}
+
+	err = p.evaluateNamespaceConfig(app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = p.evaluateOptions(app)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (p policy) evaluateCatalog(app v1alpha1.App) error {
+	if len(p.Catalogs) == 0 {
+		return nil
+	}
+
+	for _, c := range p.Catalogs {
+		if p.allowsCatalog(c, app) {
+			return nil
+		}
+	}
+
+	return microerror.Maskf(policyViolationError, catalogNotAllowedTemplate, key.CatalogName(app), key.CatalogNamespace(app), app.Namespace, p.Name)
+}
+
+func (p policy) evaluateNamespaceConfig(app v1alpha1.App) error {
+	denied := []struct {
+		kind   string
+		denied map[string]string
+		values map[string]string
+	}{
+		{kind: "label", denied: p.NamespaceConfig.DeniedLabels, values: app.Spec.NamespaceConfig.Labels},
+		{kind: "annotation", denied: p.NamespaceConfig.DeniedAnnotations, values: app.Spec.NamespaceConfig.Annotations},
+	}
+
+	for _, d := range denied {
+		for k, pattern := range d.denied {
+			v, ok := d.values[k]
+			if ok && p.matches(pattern, v) {
+				return microerror.Maskf(policyViolationError, namespaceConfigDeniedTemplate, d.kind, k, v, app.Namespace, p.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p policy) evaluateOptions(app v1alpha1.App) error {
+	if p.Options.DenySkipCRDs && app.Spec.Install.SkipCRDs {
+		return microerror.Maskf(policyViolationError, skipCRDsDeniedTemplate, app.Namespace, p.Name)
+	}
+
+	if p.Options.MaxTimeout == nil {
+		return nil
+	}
+
+	timeouts := []struct {
+		field   string
+		timeout *metav1.Duration
+	}{
+		{field: ".spec.install.timeout", timeout: app.Spec.Install.Timeout},
+		{field: ".spec.upgrade.timeout", timeout: app.Spec.Upgrade.Timeout},
+		{field: ".spec.rollback.timeout", timeout: app.Spec.Rollback.Timeout},
+		{field: ".spec.uninstall.timeout", timeout: app.Spec.Uninstall.Timeout},
+	}
+
+	for _, t := range timeouts {
+		if t.timeout != nil && t.timeout.Duration > p.Options.MaxTimeout.Duration {
+			return microerror.Maskf(policyViolationError, timeoutExceededTemplate, t.field, t.timeout.Duration, p.Options.MaxTimeout.Duration, app.Namespace, p.Name)
+		}
+	}
+
+	return nil
+}
+
+func (p policy) allowsCatalog(c Catalog, app v1alpha1.App) bool {
+	if !p.matches(c.Name, key.CatalogName(app)) {
+		return false
+	}
+	if !c.OwnNamespace && len(c.Namespaces) == 0 {
+		return true
+	}
+
+	catalogNamespace := key.CatalogNamespace(app)
+	if c.OwnNamespace && catalogNamespace == app.Namespace {
+		return true
+	}
+	for _, ns := range c.Namespaces {
+		if catalogNamespace == ns {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseAll(policies []Policy) ([]policy, error) {
+	var result []policy
+	for _, p := range policies {
+		parsed, err := parse(p)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		result = append(result, parsed)
+	}
+
+	return result, nil
+}
+
+func parse(p Policy) (policy, error) {
+	if p.Name == "" {
+		return policy{}, microerror.Maskf(invalidConfigError, "policy name must not be empty")
+	}
+
+	var patterns []string
+	for _, names := range []Names{p.Apps, p.TargetNamespaces} {
+		patterns = append(patterns, names.Allowed...)
+		patterns = append(patterns, names.Denied...)
+	}
+	for _, c := range p.Catalogs {
+		patterns = append(patterns, c.Name)
+	}
+	for _, values := range []map[string]string{p.NamespaceConfig.DeniedLabels, p.NamespaceConfig.DeniedAnnotations} {
+		for _, pattern := range values {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	parsed := policy{
+		Policy: p,
+		rules:  map[string]matcher.Matcher{},
+	}
+
+	for _, pattern := range patterns {
+		m, err := matcher.Parse(pattern, matcher.Glob)
+		if err != nil {
+			return policy{}, microerror.Maskf(invalidConfigError, "policy %#q: %s", p.Name, err)
+		}
+		parsed.rules[pattern] = m
+	}
+
+	namespaces, err := matcher.ParseList(p.Namespaces, matcher.Exact)
+	if err != nil {
+		return policy{}, microerror.Maskf(invalidConfigError, "policy %#q: %s", p.Name, err)
+	}
+	parsed.namespaces = namespaces
+
+	if p.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(p.NamespaceSelector)
+		if err != nil {
+			return policy{}, microerror.Maskf(invalidConfigError, "policy %#q has invalid namespace selector: %s", p.Name, err)
+		}
+		parsed.selector = selector
+	}
+
+	return parsed, nil
+}
+
+// allows checks if the name matches one of the allowed patterns, or if
+// there are none.
+func (p policy) allows(n Names, name string) bool {
+	return len(n.Allowed) == 0 || p.matchesAny(n.Allowed, name)
+}
+
+// denies checks if the name matches one of the denied patterns.
+func (p policy) denies(n Names, name string) bool {
+	return p.matchesAny(n.Denied, name)
+}
+
+func (p policy) matches(pattern, name string) bool {
+	return p.rules[pattern].Matches(name)
+}
+
+func (p policy) matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if p.matches(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
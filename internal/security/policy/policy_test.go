@@ -0,0 +1,242 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/errors"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_Evaluator_Evaluate(t *testing.T) {
+	policies := []Policy{
+		{
+			Name:       "acme",
+			Namespaces: []string{"org-acme"},
+			Catalogs: []Catalog{
+				{
+					Name:         "*",
+					OwnNamespace: true,
+				},
+				{
+					Name:       "giantswarm",
+					Namespaces: []string{"giantswarm"},
+				},
+			},
+			Apps: Names{
+				Denied: []string{"*-operator"},
+			},
+			TargetNamespaces: Names{
+				Denied: []string{"kube-*", "giantswarm"},
+			},
+			NamespaceConfig: NamespaceConfig{
+				DeniedLabels: map[string]string{"pod-security.kubernetes.io/enforce": "privileged"},
+			},
+			Options: Options{
+				DenySkipCRDs: true,
+				MaxTimeout:   &metav1.Duration{Duration: 10 * time.Minute},
+			},
+		},
+		{
+			Name: "restricted",
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"tier": "restricted"},
+			},
+			Apps: Names{
+				Allowed: []string{"hello-*", "regex:kiam-[0-9]+"},
+			},
+		},
+	}
+
+	newApp := func(namespace, name, catalog, catalogNamespace string) v1alpha1.App {
+		return v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: v1alpha1.AppSpec{
+				Catalog:          catalog,
+				CatalogNamespace: catalogNamespace,
+				Name:             name,
+				Namespace:        "hello",
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		app          v1alpha1.App
+		errorMatcher errors.Matcher
+	}{
+		{
+			name: "case 0: catalog in own namespace is allowed",
+			app:  newApp("org-acme", "hello-world", "acme", "org-acme"),
+		},
+		{
+			name: "case 1: public catalog is allowed",
+			app:  newApp("org-acme", "hello-world", "giantswarm", "giantswarm"),
+		},
+		{
+			name:         "case 2: catalog in another namespace is rejected",
+			app:          newApp("org-acme", "hello-world", "other", "org-other"),
+			errorMatcher: IsPolicyViolation,
+		},
+		{
+			name:         "case 3: denied app is rejected",
+			app:          newApp("org-acme", "app-operator", "giantswarm", "giantswarm"),
+			errorMatcher: IsPolicyViolation,
+		},
+		{
+			name: "case 4: namespace without policy is not restricted",
+			app:  newApp("org-other", "app-operator", "other", "org-acme"),
+		},
+		{
+			name: "case 5: allowed app in selected namespace is allowed",
+			app:  newApp("org-restricted", "hello-world", "giantswarm", "giantswarm"),
+		},
+		{
+			name:         "case 6: app not allowed in selected namespace is rejected",
+			app:          newApp("org-restricted", "kiam", "giantswarm", "giantswarm"),
+			errorMatcher: IsPolicyViolation,
+		},
+		{
+			name: "case 7: denied target namespace is rejected",
+			app: func() v1alpha1.App {
+				app := newApp("org-acme", "hello-world", "giantswarm", "giantswarm")
+				app.Spec.Namespace = "kube-system"
+				return app
+			}(),
+			errorMatcher: IsPolicyViolation,
+		},
+		{
+			name: "case 8: denied namespace label is rejected",
+			app: func() v1alpha1.App {
+				app := newApp("org-acme", "hello-world", "giantswarm", "giantswarm")
+				app.Spec.NamespaceConfig.Labels = map[string]string{"pod-security.kubernetes.io/enforce": "privileged"}
+				return app
+			}(),
+			errorMatcher: IsPolicyViolation,
+		},
+		{
+			name: "case 9: other namespace label value is allowed",
+			app: func() v1alpha1.App {
+				app := newApp("org-acme", "hello-world", "giantswarm", "giantswarm")
+				app.Spec.NamespaceConfig.Labels = map[string]string{"pod-security.kubernetes.io/enforce": "restricted"}
+				return app
+			}(),
+		},
+		{
+			name: "case 10: skipping CRDs is rejected",
+			app: func() v1alpha1.App {
+				app := newApp("org-acme", "hello-world", "giantswarm", "giantswarm")
+				app.Spec.Install.SkipCRDs = true
+				return app
+			}(),
+			errorMatcher: IsPolicyViolation,
+		},
+		{
+			name: "case 11: timeout above maximum is rejected",
+			app: func() v1alpha1.App {
+				app := newApp("org-acme", "hello-world", "giantswarm", "giantswarm")
+				app.Spec.Upgrade.Timeout = &metav1.Duration{Duration: time.Hour}
+				return app
+			}(),
+			errorMatcher: IsPolicyViolation,
+		},
+		{
+			name: "case 12: app allowed by regular expression in selected namespace is allowed",
+			app:  newApp("org-restricted", "kiam-2", "giantswarm", "giantswarm"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+				K8sClient: clientgofake.NewClientset(
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "org-acme"}},
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "org-other"}},
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "org-restricted", Labels: map[string]string{"tier": "restricted"}}},
+				),
+			})
+
+			e, err := New(Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+
+				Policies: policies,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			err = e.Evaluate(ctx, tc.app)
+
+			if tc.errorMatcher == nil {
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			} else if !tc.errorMatcher(err) {
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_Load(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policies.yaml")
+	data := `
+- name: acme
+  namespaceSelector:
+    matchLabels:
+      giantswarm.io/organization: acme
+  catalogs:
+  - name: giantswarm
+    namespaces:
+    - giantswarm
+  apps:
+    denied:
+    - "*-operator"
+`
+	err := os.WriteFile(file, []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	policies, err := Load(file)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	expected := []Policy{
+		{
+			Name: "acme",
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"giantswarm.io/organization": "acme"},
+			},
+			Catalogs: []Catalog{
+				{
+					Name:       "giantswarm",
+					Namespaces: []string{"giantswarm"},
+				},
+			},
+			Apps: Names{
+				Denied: []string{"*-operator"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(policies, expected) {
+		t.Fatalf("policies == %#v, want %#v", policies, expected)
+	}
+}
@@ -0,0 +1,67 @@
+package policy
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Policy restricts the App CRs of a tenant. It applies to App CRs in the
+// listed namespaces and in namespaces matching the selector.
+//
+// Namespaces hold matcher rules matching exactly by default, and all other
+// patterns hold matcher rules matching glob patterns by default, see the
+// matcher package.
+type Policy struct {
+	Name string `json:"name"`
+
+	Namespaces        []string              `json:"namespaces,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Catalogs lists the catalogs App CRs may be installed from. Any
+	// catalog is allowed when empty.
+	Catalogs []Catalog `json:"catalogs,omitempty"`
+	// Apps restricts the names of the apps which may be installed.
+	Apps Names `json:"apps,omitempty"`
+
+	// TargetNamespaces restricts the namespaces in the target cluster apps
+	// may be installed to, i.e. .spec.namespace of App CRs.
+	TargetNamespaces Names `json:"targetNamespaces,omitempty"`
+	// NamespaceConfig restricts the labels and annotations set on target
+	// namespaces.
+	NamespaceConfig NamespaceConfig `json:"namespaceConfig,omitempty"`
+	// Options restricts the install, upgrade, rollback and uninstall
+	// options of App CRs.
+	Options Options `json:"options,omitempty"`
+}
+
+// Catalog allows catalogs matching the name pattern. When
+// OwnNamespace or Namespaces are set, the .spec.catalogNamespace of App CRs
+// has to be the namespace of the App CR or one of the listed namespaces.
+type Catalog struct {
+	Name         string   `json:"name"`
+	OwnNamespace bool     `json:"ownNamespace,omitempty"`
+	Namespaces   []string `json:"namespaces,omitempty"`
+}
+
+// Names holds patterns for names. Denied patterns take precedence, and
+// any name not denied is allowed when Allowed is empty.
+type Names struct {
+	Allowed []string `json:"allowed,omitempty"`
+	Denied  []string `json:"denied,omitempty"`
+}
+
+// NamespaceConfig denies labels and annotations in .spec.namespaceConfig of
+// App CRs. The keys are matched exactly and the values as patterns,
+// e.g. pod-security.kubernetes.io/enforce: privileged.
+type NamespaceConfig struct {
+	DeniedLabels      map[string]string `json:"deniedLabels,omitempty"`
+	DeniedAnnotations map[string]string `json:"deniedAnnotations,omitempty"`
+}
+
+// Options gates risky Helm options of App CRs.
+type Options struct {
+	// DenySkipCRDs denies .spec.install.skipCRDs.
+	DenySkipCRDs bool `json:"denySkipCRDs,omitempty"`
+	// MaxTimeout limits the install, upgrade, rollback and uninstall
+	// timeouts.
+	MaxTimeout *metav1.Duration `json:"maxTimeout,omitempty"`
+}
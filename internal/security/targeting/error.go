@@ -0,0 +1,32 @@
+package targeting
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var crossOrganizationError = &microerror.Error{
+	Kind: "crossOrganizationError",
+}
+
+// IsCrossOrganization asserts crossOrganizationError.
+func IsCrossOrganization(err error) bool {
+	return microerror.Cause(err) == crossOrganizationError
+}
+
+var retargetingError = &microerror.Error{
+	Kind: "retargetingError",
+}
+
+// IsRetargeting asserts retargetingError.
+func IsRetargeting(err error) bool {
+	return microerror.Cause(err) == retargetingError
+}
@@ -0,0 +1,136 @@
+// Package targeting checks that App CRs in organization namespaces only
+// target workload clusters of the same organization, as app-operator uses
+// the cluster label and the kubeconfig reference of App CRs as is.
+package targeting
+
+import (
+	"context"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/app/v8/pkg/key"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	authv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+const (
+	clusterNotAllowedTemplate    = "app %#q in namespace %#q must not target cluster %#q in namespace %#q"
+	kubeConfigNotAllowedTemplate = "app %#q in namespace %#q must not reference kubeconfig secret %#q in namespace %#q"
+	retargetingTemplate          = "changing the cluster label of app %#q in namespace %#q from %#q to %#q is not allowed, as it retargets the app to another cluster"
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+
+	// Inspector decides which users are whitelisted, and so allowed to
+	// retarget App CRs.
+	Inspector *secins.Inspector
+}
+
+// Checker checks the clusters targeted by App CRs in organization
+// namespaces.
+type Checker struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+	inspector *secins.Inspector
+}
+
+func New(config Config) (*Checker, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Inspector == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Inspector must not be empty", config)
+	}
+
+	c := &Checker{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+		inspector: config.Inspector,
+	}
+
+	return c, nil
+}
+
+// Check returns an error when the App CR in an organization namespace
+// targets a Cluster CR or references a kubeconfig Secret in another
+// namespace, or when an update changes its cluster label. The current App CR
+// is nil on create. Only whitelisted users may change the cluster label.
+func (c *Checker) Check(ctx context.Context, app v1alpha1.App, currentApp *v1alpha1.App, userInfo authv1.UserInfo) error {
+	if !key.IsInOrgNamespace(app) || key.InCluster(app) {
+		return nil
+	}
+
+	cluster := key.ClusterLabel(app)
+
+	if currentApp != nil && key.ClusterLabel(*currentApp) != cluster {
+		if !c.inspector.IsWhitelisted(ctx, userInfo) {
+			return microerror.Maskf(retargetingError, retargetingTemplate, app.Name, app.Namespace, key.ClusterLabel(*currentApp), cluster)
+		}
+
+		c.logger.Debugf(ctx, "allowing retargeting of app %#q in namespace %#q by whitelisted user %#q", app.Name, app.Namespace, userInfo.Username)
+	}
+
+	if ns := key.KubeConfigSecretNamespace(app); ns != "" && ns != app.Namespace {
+		return microerror.Maskf(crossOrganizationError, kubeConfigNotAllowedTemplate, app.Name, app.Namespace, key.KubeConfigSecretName(app), ns)
+	}
+
+	if cluster == "" {
+		return nil
+	}
+
+	namespaces, err := c.clusterNamespaces(ctx, cluster, app.Namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// The Cluster CR may not exist yet, e.g. when it is created together
+	// with its apps. Only clusters found in other namespaces are rejected.
+	for _, ns := range namespaces {
+		if ns != app.Namespace {
+			return microerror.Maskf(crossOrganizationError, clusterNotAllowedTemplate, app.Name, app.Namespace, cluster, ns)
+		}
+	}
+
+	return nil
+}
+
+// clusterNamespaces returns the namespaces Cluster CRs with the given name
+// exist in. The preferred namespace is looked up first, so that listing all
+// Cluster CRs is only needed when it does not contain the cluster.
+func (c *Checker) clusterNamespaces(ctx context.Context, name, preferred string) ([]string, error) {
+	var cluster capiv1beta1.Cluster
+
+	err := c.k8sClient.CtrlClient().Get(ctx, client.ObjectKey{Name: name, Namespace: preferred}, &cluster)
+	if err == nil {
+		return []string{preferred}, nil
+	} else if !apierrors.IsNotFound(err) {
+		return nil, microerror.Mask(err)
+	}
+
+	var clusters capiv1beta1.ClusterList
+
+	err = c.k8sClient.CtrlClient().List(ctx, &clusters)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var namespaces []string
+	for _, item := range clusters.Items {
+		if item.Name == name {
+			namespaces = append(namespaces, item.Namespace)
+		}
+	}
+
+	return namespaces, nil
+}
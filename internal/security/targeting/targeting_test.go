@@ -0,0 +1,160 @@
+package targeting
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	authv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/errors"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+func Test_Checker_Check(t *testing.T) {
+	newApp := func(cluster, kubeConfigNamespace string) v1alpha1.App {
+		return v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "org-acme",
+				Labels: map[string]string{
+					"giantswarm.io/cluster": cluster,
+				},
+			},
+			Spec: v1alpha1.AppSpec{
+				KubeConfig: v1alpha1.AppSpecKubeConfig{
+					Secret: v1alpha1.AppSpecKubeConfigSecret{
+						Name:      cluster + "-kubeconfig",
+						Namespace: kubeConfigNamespace,
+					},
+				},
+			},
+		}
+	}
+	current := func(app v1alpha1.App) *v1alpha1.App {
+		return &app
+	}
+
+	tests := []struct {
+		name         string
+		app          v1alpha1.App
+		currentApp   *v1alpha1.App
+		userInfo     authv1.UserInfo
+		errorMatcher errors.Matcher
+	}{
+		{
+			name: "case 0: cluster in the same namespace is allowed",
+			app:  newApp("acme01", "org-acme"),
+		},
+		{
+			name: "case 1: cluster not existing yet is allowed",
+			app:  newApp("acme02", "org-acme"),
+		},
+		{
+			name:         "case 2: cluster in another organization is rejected",
+			app:          newApp("other01", ""),
+			errorMatcher: IsCrossOrganization,
+		},
+		{
+			name:         "case 3: kubeconfig in another organization is rejected",
+			app:          newApp("acme01", "org-other"),
+			errorMatcher: IsCrossOrganization,
+		},
+		{
+			name: "case 4: app outside of organization namespaces is not checked",
+			app: func() v1alpha1.App {
+				app := newApp("other01", "org-other")
+				app.Namespace = "acme01"
+				return app
+			}(),
+		},
+		{
+			name: "case 5: in-cluster app is not checked",
+			app: func() v1alpha1.App {
+				app := newApp("other01", "org-other")
+				app.Spec.KubeConfig.InCluster = true
+				return app
+			}(),
+		},
+		{
+			name:       "case 6: update keeping the cluster label is allowed",
+			app:        newApp("acme01", "org-acme"),
+			currentApp: current(newApp("acme01", "org-acme")),
+			userInfo:   authv1.UserInfo{Username: "jane"},
+		},
+		{
+			name:         "case 7: update changing the cluster label is rejected",
+			app:          newApp("acme02", "org-acme"),
+			currentApp:   current(newApp("acme01", "org-acme")),
+			userInfo:     authv1.UserInfo{Username: "jane"},
+			errorMatcher: IsRetargeting,
+		},
+		{
+			name:       "case 8: update changing the cluster label by whitelisted user is allowed",
+			app:        newApp("acme02", "org-acme"),
+			currentApp: current(newApp("acme01", "org-acme")),
+			userInfo:   authv1.UserInfo{Username: "jane", Groups: []string{"admins"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			clusters := []client.Object{
+				newCluster("acme01", "org-acme"),
+				newCluster("other01", "org-other"),
+			}
+
+			scheme := runtime.NewScheme()
+			_ = capiv1beta1.AddToScheme(scheme)
+
+			k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+				CtrlClient: fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusters...).Build(),
+			})
+
+			inspector, err := secins.New(secins.Config{
+				Logger:         microloggertest.New(),
+				GroupWhitelist: []string{"admins"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			c, err := New(Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Inspector: inspector,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			err = c.Check(ctx, tc.app, tc.currentApp, tc.userInfo)
+
+			if tc.errorMatcher == nil {
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			} else if !tc.errorMatcher(err) {
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func newCluster(name, namespace string) *capiv1beta1.Cluster {
+	return &capiv1beta1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+}
@@ -8,27 +8,57 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"reflect"
 	"syscall"
 	"time"
 
 	"github.com/dyson/certman"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	authv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/app-admission-controller/v2/config"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/admissionpolicy"
 	"github.com/giantswarm/app-admission-controller/v2/pkg/app"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/audit"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/backfill"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/certrotation"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/check"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/clientauth"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/kubeauth"
 	"github.com/giantswarm/app-admission-controller/v2/pkg/mutator"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/recording"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/shadow"
 	"github.com/giantswarm/app-admission-controller/v2/pkg/validator"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/webhook"
 
+	"github.com/giantswarm/app-admission-controller/v2/internal/certs"
+	"github.com/giantswarm/app-admission-controller/v2/internal/expression"
+	"github.com/giantswarm/app-admission-controller/v2/internal/fixture"
 	"github.com/giantswarm/app-admission-controller/v2/internal/recorder"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/access"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/breakglass"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/deletion"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
 	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/ownership"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/policy"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/targeting"
 )
 
 func main() {
 	err := mainWithError()
-	if err != nil {
+	if check.IsRejected(err) || recording.IsMismatch(err) || backfill.IsFailed(err) {
+		// Rejections, mismatches and failures are already reported in the
+		// output of the check, replay and backfill commands, so we only
+		// signal them with the exit code.
+		os.Exit(1)
+	} else if err != nil {
 		panic(fmt.Sprintf("%#v\n", err))
 	}
 }
@@ -43,12 +73,30 @@ func mainWithError() error {
 
 	var newLogger micrologger.Logger
 	{
-		newLogger, err = micrologger.New(micrologger.Config{})
+		c := micrologger.Config{}
+		// Commands other than serve print their reports to stdout, so
+		// logs must not get mixed with them.
+		if cfg.Command != config.CommandServe {
+			c.IOWriter = os.Stderr
+		}
+
+		newLogger, err = micrologger.New(c)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 	}
 
+	switch cfg.Command {
+	case config.CommandBackfill:
+		return runBackfill(ctx, cfg, newLogger)
+	case config.CommandCheck:
+		return runCheck(ctx, cfg, newLogger)
+	case config.CommandReplay:
+		return runReplay(cfg, newLogger)
+	case config.CommandManifests:
+		return runManifests(cfg, newLogger)
+	}
+
 	var event recorder.Interface
 	{
 		c := recorder.Config{
@@ -76,39 +124,262 @@ func mainWithError() error {
 
 	var inspector *secins.Inspector
 	{
+		inspector, err = secins.New(inspectorConfig(cfg, newLogger))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
 
-		c := secins.Config{
-			Logger: newLogger,
+	// Policy objects are compiled into the inspector and the policy
+	// evaluator, which is hence always needed with them.
+	var policyEvaluator *policy.Evaluator
+	if len(cfg.Policies) > 0 || cfg.PolicyObjects {
+		c := policy.Config{
+			K8sClient: cfg.K8sClient,
+			Logger:    newLogger,
 
-			NamespaceBlacklist: cfg.NamespaceBlacklist,
-			GroupWhitelist:     cfg.GroupWhitelist,
-			UserWhitelist:      cfg.UserWhitelist,
-			AppBlacklist:       cfg.AppBlacklist,
-			CatalogBlacklist:   cfg.CatalogBlacklist,
+			Policies: cfg.Policies,
 		}
 
-		inspector, err = secins.New(c)
+		policyEvaluator, err = policy.New(c)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 	}
 
-	var appValidator *app.Validator
-	{
-		c := app.ValidatorConfig{
-			Event:     event,
+	// Policy objects in the audit mode are compiled into dedicated
+	// instances sharing the configuration of the enforced ones.
+	var auditInspector *secins.Inspector
+	var auditPolicyEvaluator *policy.Evaluator
+	if cfg.PolicyObjects {
+		auditInspector, err = secins.New(inspectorConfig(cfg, newLogger))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		c := policy.Config{
 			K8sClient: cfg.K8sClient,
 			Logger:    newLogger,
 
-			Provider:  cfg.Provider,
-			Inspector: inspector,
+			Policies: cfg.Policies,
 		}
-		appValidator, err = app.NewValidator(c)
+
+		auditPolicyEvaluator, err = policy.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if cfg.PolicyObjects {
+		var controller *admissionpolicy.Controller
+		{
+			c := admissionpolicy.Config{
+				K8sClient: cfg.K8sClient,
+				Logger:    newLogger,
+
+				Inspector:            inspector,
+				PolicyEvaluator:      policyEvaluator,
+				AuditInspector:       auditInspector,
+				AuditPolicyEvaluator: auditPolicyEvaluator,
+
+				Namespaced: cfg.PolicyObjectsNamespaced,
+			}
+			controller, err = admissionpolicy.New(c)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		err = controller.Run(ctx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// The live validator shares the inspector and the policy evaluator
+	// policy objects are compiled into.
+	appValidator, err := newValidator(cfg, newLogger, validatorOptions{
+		Event: event,
+
+		Inspector:            inspector,
+		PolicyEvaluator:      policyEvaluator,
+		AuditInspector:       auditInspector,
+		AuditPolicyEvaluator: auditPolicyEvaluator,
+
+		BreakGlass: true,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Requests are evaluated against the candidate configuration without
+	// affecting the responses.
+	var appValidating validating = appValidator
+	if cfg.CandidateFile != "" {
+		candidate, err := shadow.Load(cfg.CandidateFile)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		candidateValidator, err := newCandidateValidator(cfg, candidate, newLogger)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		c := shadow.Config{
+			Logger: newLogger,
+
+			Live:      appValidator,
+			Candidate: candidateValidator,
+		}
+
+		appValidating, err = shadow.New(c)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 	}
 
+	if cfg.AuditInterval > 0 {
+		// Audit runs must not create any objects, hence the dedicated
+		// mutator running in the dry run mode.
+		var auditMutator *app.Mutator
+		{
+			c := app.MutatorConfig{
+				K8sClient:     cfg.K8sClient,
+				Logger:        newLogger,
+				Provider:      cfg.Provider,
+				ConfigPatches: cfg.PSPPatches,
+				DryRun:        true,
+			}
+			auditMutator, err = app.NewMutator(c)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		// Audit runs must not be mistaken for admissions, hence the
+		// dedicated validator deciding violations silently and not
+		// emitting events.
+		auditValidator, err := newValidator(cfg, newLogger, validatorOptions{
+			Inspector:       inspector,
+			PolicyEvaluator: policyEvaluator,
+
+			Silent: true,
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		var auditor *audit.Auditor
+		{
+			c := audit.Config{
+				Event:     event,
+				K8sClient: cfg.K8sClient,
+				Logger:    newLogger,
+				Mutator:   auditMutator,
+				Validator: auditValidator,
+
+				Interval:                 cfg.AuditInterval,
+				StatusConfigMapName:      cfg.AuditStatusConfigMapName,
+				StatusConfigMapNamespace: cfg.AuditStatusConfigMapNamespace,
+			}
+			auditor, err = audit.New(c)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		go auditor.Run(ctx)
+	}
+
+	hooks := webhooks(appMutator, appValidating)
+
+	// Webhook configurations are reconciled before the self-managed CA
+	// bundle is injected into them.
+	if cfg.WebhookReconcile {
+		objs, err := webhookConfigurations(cfg, hooks)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = webhook.Install(ctx, cfg.K8sClient.CtrlClient(), objs...)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		newLogger.Debugf(ctx, "reconciled webhook configurations %#q", cfg.WebhookConfigurationName)
+	}
+
+	if cfg.SelfSignedCert {
+		cfg.CertFile, cfg.KeyFile, err = writeSelfSignedCert(cfg.SelfSignedCertHosts)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		newLogger.Debugf(ctx, "generated self-signed certificate %#q", cfg.CertFile)
+	}
+
+	if cfg.SelfManagedTLS {
+		var rotator *certrotation.Rotator
+		{
+			c := certrotation.Config{
+				K8sClient: cfg.K8sClient,
+				Logger:    newLogger,
+
+				CertFile:                 cfg.CertFile,
+				KeyFile:                  cfg.KeyFile,
+				Hosts:                    cfg.SelfManagedTLSHosts,
+				SecretName:               cfg.SelfManagedTLSSecretName,
+				SecretNamespace:          cfg.SelfManagedTLSSecretNamespace,
+				WebhookConfigurationName: cfg.WebhookConfigurationName,
+			}
+			rotator, err = certrotation.New(c)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		// The serving certificate has to be written before it is loaded
+		// below. Renewed certificates are reloaded by the watcher.
+		err = rotator.Ensure(ctx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		go rotator.Run(ctx)
+	}
+
+	if cfg.DevWebhookURL != "" {
+		objs, err := devWebhookConfigurations(cfg, hooks)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if cfg.DevWebhookInstall {
+			err = webhook.Install(ctx, cfg.K8sClient.CtrlClient(), objs...)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			newLogger.Debugf(ctx, "installed webhook configurations pointing at %#q", cfg.DevWebhookURL)
+
+			defer func() {
+				err := webhook.Uninstall(context.Background(), cfg.K8sClient.CtrlClient(), objs...)
+				if err != nil {
+					newLogger.Errorf(ctx, err, "unable to remove webhook configurations")
+					return
+				}
+
+				newLogger.Debugf(ctx, "removed webhook configurations pointing at %#q", cfg.DevWebhookURL)
+			}()
+		} else {
+			err = webhook.Print(os.Stdout, objs...)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+	}
+
 	cm, err := certman.New(cfg.CertFile, cfg.KeyFile)
 	if err != nil {
 		return microerror.Mask(err)
@@ -118,10 +389,59 @@ func mainWithError() error {
 		panic(microerror.JSON(err))
 	}
 
-	// Here we register our endpoints.
+	var rec *recording.Recorder
+	if cfg.RecordFile != "" {
+		f, err := os.OpenFile(cfg.RecordFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		defer f.Close() //nolint:errcheck
+
+		c := recording.Config{
+			Logger: newLogger,
+			Output: f,
+
+			SampleRate: cfg.RecordSampleRate,
+		}
+		rec, err = recording.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var auth *clientauth.Authenticator
+	if cfg.ClientCAFile != "" {
+		c := clientauth.Config{
+			Logger: newLogger,
+
+			AllowedSubjects: cfg.ClientAllowedSubjects,
+			CAFile:          cfg.ClientCAFile,
+		}
+		auth, err = clientauth.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = auth.Watch(ctx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// Here we register our endpoints. Client certificates are verified
+	// before anything gets recorded. The health check is not wrapped, as
+	// kubelet probes do not present client certificates.
 	handler := http.NewServeMux()
-	handler.Handle("/mutate/app", mutator.Handler(appMutator))
-	handler.Handle("/validate/app", validator.Handler(appValidator))
+	for _, w := range hooks {
+		h := w.Handler
+		if rec != nil {
+			h = rec.Handler(h)
+		}
+		if auth != nil {
+			h = auth.Handler(h)
+		}
+		handler.Handle(w.Path, h)
+	}
 
 	handler.HandleFunc("/healthz", func(writer http.ResponseWriter, request *http.Request) {
 		healthCheck(writer, request, cm, cfg.CertFile, cfg.KeyFile)
@@ -132,12 +452,612 @@ func mainWithError() error {
 
 	newLogger.Debugf(ctx, "listening on port %s", cfg.Address)
 
-	go serveMetrics(cfg, metrics)
+	// Metrics and any debug endpoints added to the metrics server are only
+	// served to authorised clients in the secure mode, replacing a
+	// kube-rbac-proxy sidecar.
+	var metricsHandler http.Handler = metrics
+	if cfg.MetricsSecure {
+		var authorizer *kubeauth.Authorizer
+		{
+			c := kubeauth.Config{
+				K8sClient: cfg.K8sClient,
+				Logger:    newLogger,
+
+				Audiences: cfg.MetricsAuthAudiences,
+			}
+
+			if cfg.MetricsAuthResource != "" {
+				c.ResourceAttributes = &authorizationv1.ResourceAttributes{
+					Namespace:   cfg.MetricsAuthNamespace,
+					Group:       cfg.MetricsAuthAPIGroup,
+					Resource:    cfg.MetricsAuthResource,
+					Subresource: cfg.MetricsAuthSubresource,
+					Name:        cfg.MetricsAuthName,
+				}
+			}
+
+			authorizer, err = kubeauth.New(c)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		metricsHandler = authorizer.Handler(metrics)
+	}
+
+	go serveMetrics(cfg, cm, metricsHandler)
 	serveTLS(cfg, cm, handler)
 
 	return nil
 }
 
+func runBackfill(ctx context.Context, cfg config.Config, logger micrologger.Logger) error {
+	var err error
+
+	// Unless the patches are applied, the mutator runs in the dry run mode
+	// so that reporting does not create any objects.
+	var appMutator *app.Mutator
+	{
+		c := app.MutatorConfig{
+			K8sClient:     cfg.K8sClient,
+			Logger:        logger,
+			Provider:      cfg.Provider,
+			ConfigPatches: cfg.PSPPatches,
+			DryRun:        !cfg.BackfillApply,
+		}
+		appMutator, err = app.NewMutator(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var backfiller *backfill.Backfiller
+	{
+		c := backfill.Config{
+			K8sClient: cfg.K8sClient,
+			Logger:    logger,
+			Mutator:   appMutator,
+			Output:    os.Stdout,
+
+			Apply:     cfg.BackfillApply,
+			Namespace: cfg.BackfillNamespace,
+			Rate:      cfg.BackfillRate,
+			Selector:  cfg.BackfillSelector,
+		}
+		backfiller, err = backfill.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	_, err = backfiller.Run(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func runCheck(ctx context.Context, cfg config.Config, logger micrologger.Logger) error {
+	fixtures, err := fixture.Load(cfg.CheckFixtures...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	apps, err := fixture.Load(cfg.CheckFiles...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	k8sClient := fixture.NewClients(fixtures)
+
+	appMutator, appValidator, err := newOfflineAdmitters(cfg, k8sClient, logger)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var checker *check.Checker
+	{
+		c := check.Config{
+			K8sClient: k8sClient,
+			Logger:    logger,
+			Mutator:   appMutator,
+			Output:    os.Stdout,
+			Validator: appValidator,
+
+			UserInfo: authv1.UserInfo{
+				Username: cfg.CheckUser,
+				Groups:   cfg.CheckGroups,
+			},
+		}
+		checker, err = check.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	_, err = checker.Run(ctx, apps)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func runReplay(cfg config.Config, logger micrologger.Logger) error {
+	fixtures, err := fixture.Load(cfg.ReplayFixtures...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	f, err := os.Open(cfg.ReplayFile)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	entries, err := recording.Read(f)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	appMutator, appValidator, err := newOfflineAdmitters(cfg, fixture.NewClients(fixtures), logger)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	handler := http.NewServeMux()
+	for _, w := range webhooks(appMutator, appValidator) {
+		handler.Handle(w.Path, w.Handler)
+	}
+
+	_, err = recording.Replay(handler, entries, os.Stdout)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func runManifests(cfg config.Config, logger micrologger.Logger) error {
+	// The admitters are only created to collect their webhook
+	// registrations.
+	appMutator, appValidator, err := newOfflineAdmitters(cfg, fixture.NewClients(nil), logger)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	objs, err := webhookConfigurations(cfg, webhooks(appMutator, appValidator))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = webhook.Print(os.Stdout, objs...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// validatorOptions hold what the validators of the webhooks, audit runs,
+// candidates and offline commands differ in. All checks are created from
+// the config, so that they apply to all of them.
+type validatorOptions struct {
+	// Event emits the events of the validator, which are discarded when
+	// nil.
+	Event recorder.Interface
+	// K8sClient defaults to the one of the config. Offline commands pass
+	// clients serving fixture objects.
+	K8sClient k8sclient.Interface
+
+	// Inspector and PolicyEvaluator are created from the config when nil.
+	// Validators sharing the instances policy objects are compiled into
+	// pass them, as do AuditInspector and AuditPolicyEvaluator.
+	Inspector            *secins.Inspector
+	PolicyEvaluator      *policy.Evaluator
+	AuditInspector       *secins.Inspector
+	AuditPolicyEvaluator *policy.Evaluator
+
+	// BreakGlass enables the verification of break-glass tokens. Audit runs
+	// do not verify them, so that bypassed violations are still reported.
+	BreakGlass bool
+	// Silent keeps the decisions of the validator out of the metrics and
+	// logs of admissions, for validators not deciding admissions.
+	Silent bool
+}
+
+// newValidator creates an App CR validator with the checks of the config.
+func newValidator(cfg config.Config, logger micrologger.Logger, options validatorOptions) (*app.Validator, error) {
+	var err error
+
+	event := options.Event
+	if event == nil {
+		event = recorder.Discard{}
+	}
+
+	k8sClient := options.K8sClient
+	if k8sClient == nil {
+		k8sClient = cfg.K8sClient
+	}
+
+	inspector := options.Inspector
+	if inspector == nil {
+		inspector, err = secins.New(inspectorConfig(cfg, logger))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	policyEvaluator := options.PolicyEvaluator
+	if policyEvaluator == nil && len(cfg.Policies) > 0 {
+		c := policy.Config{
+			K8sClient: k8sClient,
+			Logger:    logger,
+
+			Policies: cfg.Policies,
+		}
+
+		policyEvaluator, err = policy.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var accessReviewer *access.Reviewer
+	if cfg.ReferenceAccessReview {
+		c := access.Config{
+			K8sClient: k8sClient,
+			Logger:    logger,
+		}
+
+		accessReviewer, err = access.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var breakGlassVerifier *breakglass.Verifier
+	if options.BreakGlass && cfg.BreakGlassSecretName != "" {
+		c := breakglass.Config{
+			K8sClient: k8sClient,
+			Logger:    logger,
+
+			SecretName:      cfg.BreakGlassSecretName,
+			SecretNamespace: cfg.BreakGlassSecretNamespace,
+			Rules:           cfg.BreakGlassRules,
+			Silent:          options.Silent,
+		}
+
+		breakGlassVerifier, err = breakglass.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var targetingChecker *targeting.Checker
+	if cfg.RestrictClusterTargeting {
+		c := targeting.Config{
+			K8sClient: k8sClient,
+			Logger:    logger,
+			Inspector: inspector,
+		}
+
+		targetingChecker, err = targeting.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var ownershipChecker *ownership.Checker
+	if len(cfg.OwnerTeams) > 0 {
+		c := ownership.Config{
+			Logger:    logger,
+			Inspector: inspector,
+
+			Teams: cfg.OwnerTeams,
+		}
+
+		ownershipChecker, err = ownership.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var deletionGuard *deletion.Guard
+	if len(cfg.CriticalAppNames) > 0 || len(cfg.CriticalAppCatalogs) > 0 || len(cfg.CriticalAppLabels) > 0 {
+		c := deletion.Config{
+			K8sClient: k8sClient,
+			Logger:    logger,
+			Inspector: inspector,
+
+			Names:    cfg.CriticalAppNames,
+			Catalogs: cfg.CriticalAppCatalogs,
+			Labels:   cfg.CriticalAppLabels,
+		}
+
+		deletionGuard, err = deletion.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var ruleEvaluator *expression.Evaluator
+	if len(cfg.Rules) > 0 {
+		c := expression.Config{
+			K8sClient: k8sClient,
+			Logger:    logger,
+
+			Rules:  cfg.Rules,
+			Silent: options.Silent,
+		}
+
+		ruleEvaluator, err = expression.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var enforcer *enforcement.Enforcer
+	{
+		c := enforcement.Config{
+			Logger: logger,
+
+			Modes:    cfg.RuleModes,
+			Override: cfg.EnforcementOverride,
+			Silent:   options.Silent,
+		}
+
+		enforcer, err = enforcement.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var appValidator *app.Validator
+	{
+		c := app.ValidatorConfig{
+			Event:     event,
+			K8sClient: k8sClient,
+			Logger:    logger,
+
+			Provider:            cfg.Provider,
+			Inspector:           inspector,
+			ProtectedFields:     cfg.ProtectedFields,
+			ProtectedFinalizers: cfg.ProtectedFinalizers,
+			ProtectedLabels:     cfg.ProtectedLabels,
+			AccessReviewer:      accessReviewer,
+			BreakGlassVerifier:  breakGlassVerifier,
+			OwnershipChecker:    ownershipChecker,
+			DeletionGuard:       deletionGuard,
+			PolicyEvaluator:     policyEvaluator,
+			TargetingChecker:    targetingChecker,
+			RuleEvaluator:       ruleEvaluator,
+			Enforcer:            enforcer,
+			Silent:              options.Silent,
+
+			AuditInspector:       options.AuditInspector,
+			AuditPolicyEvaluator: options.AuditPolicyEvaluator,
+		}
+		appValidator, err = app.NewValidator(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return appValidator, nil
+}
+
+// inspectorConfig returns the configuration of the security inspector.
+func inspectorConfig(cfg config.Config, logger micrologger.Logger) secins.Config {
+	return secins.Config{
+		Logger: logger,
+
+		NamespaceBlacklist: cfg.NamespaceBlacklist,
+		GroupWhitelist:     cfg.GroupWhitelist,
+		UserWhitelist:      cfg.UserWhitelist,
+		AppBlacklist:       cfg.AppBlacklist,
+		CatalogBlacklist:   cfg.CatalogBlacklist,
+
+		RestrictPrivilegedFields: cfg.RestrictPrivilegedFields,
+		PrivilegedGroups:         cfg.PrivilegedGroups,
+		PrivilegedUsers:          cfg.PrivilegedUsers,
+	}
+}
+
+// newCandidateValidator creates a validator of the candidate configuration.
+// Candidates are evaluated in the background, so their decisions must not
+// be reported as admissions.
+func newCandidateValidator(cfg config.Config, candidate shadow.Candidate, logger micrologger.Logger) (*app.Validator, error) {
+	candidateValidator, err := newValidator(candidateConfig(cfg, candidate), logger, validatorOptions{
+		BreakGlass: true,
+		Silent:     true,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return candidateValidator, nil
+}
+
+// candidateConfig returns the config with the security configuration
+// replaced by the candidate one. The other checks are kept, so that they
+// are shared with the live configuration. The enforcement override is an
+// incident measure of the live configuration, which is not applied to the
+// candidate.
+func candidateConfig(cfg config.Config, candidate shadow.Candidate) config.Config {
+	cfg.NamespaceBlacklist = candidate.Blacklist.Namespaces
+	cfg.GroupWhitelist = candidate.Whitelist.Groups
+	cfg.UserWhitelist = candidate.Whitelist.Users
+	cfg.AppBlacklist = candidate.Blacklist.Apps
+	cfg.CatalogBlacklist = candidate.Blacklist.Catalogs
+
+	cfg.RestrictPrivilegedFields = candidate.PrivilegedFields.Restricted
+	cfg.PrivilegedGroups = candidate.PrivilegedFields.Groups
+	cfg.PrivilegedUsers = candidate.PrivilegedFields.Users
+
+	cfg.Policies = candidate.Policies
+	cfg.Rules = candidate.Rules
+	cfg.RuleModes = candidate.Modes
+	cfg.EnforcementOverride = ""
+
+	return cfg
+}
+
+// newOfflineAdmitters creates admitters for commands running without a
+// cluster, against clients serving fixture objects.
+func newOfflineAdmitters(cfg config.Config, k8sClient k8sclient.Interface, logger micrologger.Logger) (*app.Mutator, *app.Validator, error) {
+	var err error
+
+	var event recorder.Interface
+	{
+		c := recorder.Config{
+			K8sClient: k8sClient,
+
+			Component: "app-admission-controller",
+		}
+
+		event = recorder.New(c)
+	}
+
+	var appMutator *app.Mutator
+	{
+		c := app.MutatorConfig{
+			K8sClient:     k8sClient,
+			Logger:        logger,
+			Provider:      cfg.Provider,
+			ConfigPatches: cfg.PSPPatches,
+			DryRun:        true,
+		}
+		appMutator, err = app.NewMutator(c)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+	}
+
+	appValidator, err := newValidator(cfg, logger, validatorOptions{
+		Event:     event,
+		K8sClient: k8sClient,
+
+		BreakGlass: true,
+	})
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	return appMutator, appValidator, nil
+}
+
+// validating is the App CR validator, which may be shadowed by a candidate.
+type validating interface {
+	validator.Validator
+	Webhook() webhook.Registration
+}
+
+// webhooks returns the admission webhooks served by the webhooks server.
+// Webhook configurations are generated from their registrations.
+func webhooks(appMutator *app.Mutator, appValidator validating) []webhook.Webhook {
+	return []webhook.Webhook{
+		{
+			Registration: appMutator.Webhook(),
+			Handler:      mutator.Handler(appMutator),
+			Type:         webhook.Mutating,
+		},
+		{
+			Registration: appValidator.Webhook(),
+			Handler:      validator.Handler(appValidator),
+			Type:         webhook.Validating,
+		},
+	}
+}
+
+// webhookConfigurations returns the webhook configurations pointing the API
+// server at the webhooks server Service, or at the URL given to the
+// manifests command.
+func webhookConfigurations(cfg config.Config, hooks []webhook.Webhook) ([]client.Object, error) {
+	c := webhook.Config{
+		Name: cfg.WebhookConfigurationName,
+
+		ServiceName:      cfg.WebhookServiceName,
+		ServiceNamespace: cfg.WebhookServiceNamespace,
+		URL:              cfg.ManifestsURL,
+	}
+
+	if cfg.WebhookCertManagerCertificate != "" {
+		c.Annotations = map[string]string{
+			"cert-manager.io/inject-ca-from": cfg.WebhookCertManagerCertificate,
+		}
+	}
+
+	if cfg.ManifestsCAFile != "" {
+		caBundle, err := os.ReadFile(cfg.ManifestsCAFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		c.CABundle = caBundle
+	}
+
+	mutating, validating, err := webhook.Configurations(c, hooks)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return []client.Object{mutating, validating}, nil
+}
+
+// writeSelfSignedCert generates a self-signed certificate and writes it to a
+// temporary directory, so it is served the same way as certificates mounted
+// from Secrets.
+func writeSelfSignedCert(hosts []string) (string, string, error) {
+	cert, key, err := certs.SelfSigned(hosts, 365*24*time.Hour)
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	dir, err := os.MkdirTemp("", "app-admission-controller-")
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	err = os.WriteFile(certFile, cert, 0600)
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+	err = os.WriteFile(keyFile, key, 0600)
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	return certFile, keyFile, nil
+}
+
+// devWebhookConfigurations returns webhook configurations pointing the API
+// server at the webhooks server running out of cluster. The serving
+// certificate is used as the CA bundle, which works for self-signed
+// certificates.
+func devWebhookConfigurations(cfg config.Config, hooks []webhook.Webhook) ([]client.Object, error) {
+	caBundle, err := os.ReadFile(cfg.CertFile)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c := webhook.Config{
+		Name:     "app-admission-controller-dev",
+		URL:      cfg.DevWebhookURL,
+		CABundle: caBundle,
+	}
+
+	mutating, validating, err := webhook.Configurations(c, hooks)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return []client.Object{mutating, validating}, nil
+}
+
 func healthCheck(writer http.ResponseWriter, request *http.Request, cm *certman.CertMan, crtFile, keyFile string) {
 	inMemCrt, err := cm.GetCertificate(nil)
 	if err != nil {
@@ -175,8 +1095,15 @@ func serveTLS(config config.Config, cm *certman.CertMan, handler http.Handler) {
 		ReadHeaderTimeout: 60 * time.Second,
 	}
 
+	// Client certificates are requested but verified by the webhook
+	// handlers, so that the CA bundle can be reloaded and the health check
+	// works without them.
+	if config.ClientCAFile != "" {
+		server.TLSConfig.ClientAuth = tls.RequestClientCert
+	}
+
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGTERM)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
 	go func() {
 		<-sig
 		err := server.Shutdown(context.Background())
@@ -193,15 +1120,22 @@ func serveTLS(config config.Config, cm *certman.CertMan, handler http.Handler) {
 	}
 }
 
-func serveMetrics(config config.Config, handler http.Handler) {
+func serveMetrics(config config.Config, cm *certman.CertMan, handler http.Handler) {
 	server := &http.Server{
 		Addr:              config.MetricsAddress,
 		Handler:           handler,
 		ReadHeaderTimeout: 60 * time.Second,
 	}
 
+	if config.MetricsSecure {
+		server.TLSConfig = &tls.Config{
+			GetCertificate: cm.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+	}
+
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGTERM)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
 	go func() {
 		<-sig
 		err := server.Shutdown(context.Background())
@@ -210,7 +1144,12 @@ func serveMetrics(config config.Config, handler http.Handler) {
 		}
 	}()
 
-	err := server.ListenAndServe()
+	var err error
+	if config.MetricsSecure {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
 	if err != nil {
 		if err != http.ErrServerClosed {
 			panic(microerror.JSON(err))
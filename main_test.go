@@ -1,13 +1,33 @@
 package main
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/dyson/certman"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/app-admission-controller/v2/config"
+	"github.com/giantswarm/app-admission-controller/v2/internal/expression"
+	"github.com/giantswarm/app-admission-controller/v2/internal/fixture"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/breakglass"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/metrics"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/shadow"
 )
 
 func Test_healthCheck(t *testing.T) {
@@ -106,3 +126,163 @@ func copyCertificate(path string) error {
 
 	return nil
 }
+
+func Test_newCandidateValidator(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	objs := []runtime.Object{
+		&v1alpha1.Catalog{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "application.giantswarm.io/v1alpha1", Kind: "Catalog"},
+			ObjectMeta: metav1.ObjectMeta{Name: "giantswarm", Namespace: "default"},
+		},
+		&corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: "break-glass", Namespace: "giantswarm"},
+			Data:       map[string][]byte{"incident": key},
+		},
+	}
+
+	cfg := config.Config{
+		K8sClient: fixture.NewClients(objs),
+		Provider:  "aws",
+
+		BreakGlassSecretName:      "break-glass",
+		BreakGlassSecretNamespace: "giantswarm",
+		BreakGlassRules:           []string{"rule"},
+	}
+
+	candidate := shadow.Candidate{
+		Rules: []expression.Rule{
+			{
+				Name:       "candidate-only",
+				Expression: "false",
+				Message:    "rejected by the candidate",
+			},
+		},
+		Modes: map[string]enforcement.Mode{
+			enforcement.ExpressionRule("candidate-only"): enforcement.Warn,
+		},
+	}
+
+	v, err := newCandidateValidator(cfg, candidate, microloggertest.New())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	newToken := func(namespace string) string {
+		data, err := json.Marshal(breakglass.Token{
+			Key:       "incident",
+			App:       "hello-world",
+			Namespace: namespace,
+			Expires:   time.Now().Add(time.Hour),
+			Reason:    "INC-1",
+		})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		payload := base64.RawURLEncoding.EncodeToString(data)
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(payload))
+
+		return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		token     string
+	}{
+		{
+			name:      "case 0: rule violation in the warn mode",
+			namespace: "demo0",
+		},
+		{
+			name:      "case 1: rule violation bypassed with break-glass token",
+			namespace: "demo1",
+			token:     newToken("demo1"),
+		},
+		{
+			name:      "case 2: invalid break-glass token",
+			namespace: "demo2",
+			token:     "invalid",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cr := v1alpha1.App{
+				TypeMeta: v1alpha1.NewAppTypeMeta(),
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hello-world",
+					Namespace: tc.namespace,
+					Labels: map[string]string{
+						"app-operator.giantswarm.io/version": "0.0.0",
+					},
+				},
+				Spec: v1alpha1.AppSpec{
+					Catalog:   "giantswarm",
+					Name:      "hello-world",
+					Namespace: tc.namespace,
+					KubeConfig: v1alpha1.AppSpecKubeConfig{
+						InCluster: true,
+					},
+					Version: "0.3.0",
+				},
+			}
+			if tc.token != "" {
+				cr.Annotations = map[string]string{breakglass.Annotation: tc.token}
+			}
+
+			raw, err := json.Marshal(cr)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			rule := enforcement.ExpressionRule("candidate-only")
+			counters := map[string]func() float64{
+				"rule evaluations": func() float64 {
+					return testutil.ToFloat64(metrics.RuleEvaluations.WithLabelValues("candidate-only", "denied"))
+				},
+				"rule evaluation durations": func() float64 {
+					return float64(testutil.CollectAndCount(metrics.RuleEvaluationDuration))
+				},
+				"rule decisions": func() float64 {
+					return testutil.ToFloat64(metrics.RuleDecisions.WithLabelValues(rule, string(enforcement.Warn)))
+				},
+				"break-glass bypasses": func() float64 {
+					return testutil.ToFloat64(metrics.BreakGlassBypasses.WithLabelValues(rule))
+				},
+				"break-glass rejections": func() float64 {
+					return testutil.ToFloat64(metrics.BreakGlassRejections.WithLabelValues(tc.namespace))
+				},
+			}
+
+			before := map[string]float64{}
+			for name, value := range counters {
+				before[name] = value()
+			}
+
+			// The candidate evaluates shadowed requests, which are
+			// decided by the live validator only.
+			_, _, err = v.ValidateWithWarnings(&admissionv1.AdmissionRequest{
+				Name:      cr.Name,
+				Namespace: cr.Namespace,
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+				UserInfo:  authv1.UserInfo{Username: "jane"},
+			})
+			if tc.token == "invalid" && !breakglass.IsInvalidToken(err) {
+				t.Fatalf("error == %#v, want invalid token", err)
+			} else if tc.token != "invalid" && err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			for name, value := range counters {
+				if v := value(); v != before[name] {
+					t.Fatalf("%s == %v, want %v", name, v, before[name])
+				}
+			}
+		})
+	}
+}
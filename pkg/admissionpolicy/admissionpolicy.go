@@ -0,0 +1,425 @@
+// Package admissionpolicy watches AppAdmissionPolicy and
+// NamespacedAppAdmissionPolicy objects and compiles them into the
+// inspector and the policy evaluator used for admission, so that the
+// security configuration can be changed without a release and delegated per
+// team.
+package admissionpolicy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/policy"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+const (
+	reasonAudited  = "Audited"
+	reasonCompiled = "Compiled"
+	reasonEnforced = "Enforced"
+	reasonInvalid  = "Invalid"
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+
+	// Inspector and PolicyEvaluator are updated with the policies in the
+	// Enforce mode.
+	Inspector       *secins.Inspector
+	PolicyEvaluator *policy.Evaluator
+	// AuditInspector and AuditPolicyEvaluator are optionally updated with
+	// the policies in both the Enforce and the Audit mode. Policies in the
+	// Audit mode are ignored without them.
+	AuditInspector       *secins.Inspector
+	AuditPolicyEvaluator *policy.Evaluator
+
+	// Namespaced enables NamespacedAppAdmissionPolicy objects.
+	Namespaced bool
+	// ResyncPeriod defaults to 10 minutes.
+	ResyncPeriod time.Duration
+}
+
+// Controller compiles policy objects whenever any of them changes.
+type Controller struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+
+	inspector            *secins.Inspector
+	policyEvaluator      *policy.Evaluator
+	auditInspector       *secins.Inspector
+	auditPolicyEvaluator *policy.Evaluator
+
+	namespaced   bool
+	resyncPeriod time.Duration
+}
+
+// compiled is the outcome of compiling a single policy object.
+type compiled struct {
+	object      *unstructured.Unstructured
+	resource    schema.GroupVersionResource
+	enforcement Enforcement
+	inspector   *secins.Config
+	policies    []policy.Policy
+	err         error
+}
+
+func New(config Config) (*Controller, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Inspector == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Inspector must not be empty", config)
+	}
+	if config.PolicyEvaluator == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.PolicyEvaluator must not be empty", config)
+	}
+	if (config.AuditInspector == nil) != (config.AuditPolicyEvaluator == nil) {
+		return nil, microerror.Maskf(invalidConfigError, "%T.AuditInspector and %T.AuditPolicyEvaluator must be set together", config, config)
+	}
+
+	if config.ResyncPeriod == 0 {
+		config.ResyncPeriod = 10 * time.Minute
+	}
+
+	c := &Controller{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		inspector:            config.Inspector,
+		policyEvaluator:      config.PolicyEvaluator,
+		auditInspector:       config.AuditInspector,
+		auditPolicyEvaluator: config.AuditPolicyEvaluator,
+
+		namespaced:   config.Namespaced,
+		resyncPeriod: config.ResyncPeriod,
+	}
+
+	return c, nil
+}
+
+// Run watches the policy objects and compiles them on every change until
+// the context is done. It returns once the initial policies are compiled,
+// so that no request is admitted without them.
+func (c *Controller) Run(ctx context.Context) error {
+	resources := []schema.GroupVersionResource{ClusterResource}
+	if c.namespaced {
+		resources = append(resources, NamespacedResource)
+	}
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(c.k8sClient.DynClient(), c.resyncPeriod)
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+		DeleteFunc: func(interface{}) { notify() },
+	}
+
+	stores := map[schema.GroupVersionResource]cache.Store{}
+	for _, r := range resources {
+		informer := factory.ForResource(r).Informer()
+		_, err := informer.AddEventHandler(handler)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		stores[r] = informer.GetStore()
+	}
+
+	factory.Start(ctx.Done())
+	for r, ok := range factory.WaitForCacheSync(ctx.Done()) {
+		if !ok {
+			return microerror.Maskf(invalidConfigError, "unable to sync %s", r.String())
+		}
+	}
+
+	// Drain notifications of the initial listing, which the first sync
+	// below covers.
+	select {
+	case <-changed:
+	default:
+	}
+
+	err := c.sync(ctx, stores)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+				err := c.sync(ctx, stores)
+				if err != nil {
+					c.logger.Errorf(ctx, err, "failed to sync policy objects")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// sync compiles all policy objects, updates the inspectors and evaluators,
+// and reports the outcome in the status of the objects.
+func (c *Controller) sync(ctx context.Context, stores map[schema.GroupVersionResource]cache.Store) error {
+	var all []compiled
+	for _, r := range []schema.GroupVersionResource{ClusterResource, NamespacedResource} {
+		store, ok := stores[r]
+		if !ok {
+			continue
+		}
+
+		objs := store.List()
+		sort.Slice(objs, func(i, j int) bool {
+			return key(objs[i]) < key(objs[j])
+		})
+
+		for _, obj := range objs {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+
+			all = append(all, compile(r, u))
+		}
+	}
+
+	var enforcedConfigs, auditConfigs []secins.Config
+	var enforcedPolicies, auditPolicies []policy.Policy
+	for _, p := range all {
+		if p.err != nil {
+			continue
+		}
+
+		if p.inspector != nil {
+			auditConfigs = append(auditConfigs, *p.inspector)
+		}
+		auditPolicies = append(auditPolicies, p.policies...)
+
+		if p.enforcement == EnforcementEnforce {
+			if p.inspector != nil {
+				enforcedConfigs = append(enforcedConfigs, *p.inspector)
+			}
+			enforcedPolicies = append(enforcedPolicies, p.policies...)
+		}
+	}
+
+	err := c.inspector.Update(enforcedConfigs...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = c.policyEvaluator.Update(enforcedPolicies)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if c.auditInspector != nil {
+		err = c.auditInspector.Update(auditConfigs...)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		err = c.auditPolicyEvaluator.Update(auditPolicies)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	c.logger.Debugf(ctx, "compiled %d policy objects", len(all))
+
+	for _, p := range all {
+		err = c.updateStatus(ctx, p)
+		if err != nil {
+			c.logger.Errorf(ctx, err, "failed to update status of policy object %#q", key(p.object))
+		}
+	}
+
+	return nil
+}
+
+func (c *Controller) updateStatus(ctx context.Context, p compiled) error {
+	var status Status
+	if s, ok := p.object.Object["status"].(map[string]interface{}); ok {
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(s, &status)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	current := Status{
+		ObservedGeneration: status.ObservedGeneration,
+		Conditions:         append([]metav1.Condition{}, status.Conditions...),
+	}
+
+	generation := p.object.GetGeneration()
+	status.ObservedGeneration = generation
+
+	if p.err != nil {
+		apimeta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: ConditionValid, Status: metav1.ConditionFalse, Reason: reasonInvalid, Message: p.err.Error(), ObservedGeneration: generation})
+		apimeta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: ConditionActive, Status: metav1.ConditionFalse, Reason: reasonInvalid, Message: "The policy is not used as it is invalid.", ObservedGeneration: generation})
+	} else {
+		apimeta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: ConditionValid, Status: metav1.ConditionTrue, Reason: reasonCompiled, Message: "The policy compiled.", ObservedGeneration: generation})
+
+		active := metav1.Condition{Type: ConditionActive, Status: metav1.ConditionTrue, Reason: reasonEnforced, Message: "Violations are rejected.", ObservedGeneration: generation}
+		if p.enforcement == EnforcementAudit {
+			active.Reason = reasonAudited
+			active.Message = "Violations are logged and counted, but admitted."
+			if c.auditInspector == nil {
+				active.Status = metav1.ConditionFalse
+				active.Message = "The policy is not used as auditing is disabled."
+			}
+		}
+		apimeta.SetStatusCondition(&status.Conditions, active)
+	}
+
+	if reflect.DeepEqual(current, status) {
+		return nil
+	}
+
+	s, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	u := p.object.DeepCopy()
+	u.Object["status"] = s
+
+	_, err = c.k8sClient.DynClient().Resource(p.resource).Namespace(u.GetNamespace()).UpdateStatus(ctx, u, metav1.UpdateOptions{})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// compile converts the policy object into the configuration of the
+// inspector and the policy evaluator, and validates it.
+func compile(r schema.GroupVersionResource, u *unstructured.Unstructured) compiled {
+	result := compiled{
+		object:   u,
+		resource: r,
+	}
+
+	var enforcement Enforcement
+	var err error
+	if r == ClusterResource {
+		enforcement, result.inspector, result.policies, err = compileCluster(u)
+	} else {
+		enforcement, result.policies, err = compileNamespaced(u)
+	}
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	switch enforcement {
+	case "", EnforcementEnforce:
+		result.enforcement = EnforcementEnforce
+	case EnforcementAudit:
+		result.enforcement = EnforcementAudit
+	default:
+		result.err = microerror.Maskf(invalidPolicyError, "enforcement %#q is not one of %#q and %#q", enforcement, EnforcementEnforce, EnforcementAudit)
+		return result
+	}
+
+	if result.inspector != nil {
+		err = secins.Validate(*result.inspector)
+		if err != nil {
+			result.err = err
+			return result
+		}
+	}
+
+	for _, p := range result.policies {
+		err = policy.Validate(p)
+		if err != nil {
+			result.err = err
+			return result
+		}
+	}
+
+	return result
+}
+
+func compileCluster(u *unstructured.Unstructured) (Enforcement, *secins.Config, []policy.Policy, error) {
+	var obj AppAdmissionPolicy
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &obj)
+	if err != nil {
+		return "", nil, nil, microerror.Maskf(invalidPolicyError, "%s", err)
+	}
+
+	config := &secins.Config{
+		AppBlacklist:       obj.Spec.Blacklist.Apps,
+		CatalogBlacklist:   obj.Spec.Blacklist.Catalogs,
+		GroupWhitelist:     obj.Spec.Whitelist.Groups,
+		NamespaceBlacklist: obj.Spec.Blacklist.Namespaces,
+		UserWhitelist:      obj.Spec.Whitelist.Users,
+	}
+
+	var policies []policy.Policy
+	for i, t := range obj.Spec.Tenants {
+		// Names identify the policy in rejections, so they are prefixed
+		// with the object name.
+		name := t.Name
+		if name == "" {
+			name = fmt.Sprint(i)
+		}
+		t.Name = fmt.Sprintf("%s/%s", obj.Name, name)
+
+		policies = append(policies, t)
+	}
+
+	return obj.Spec.Enforcement, config, policies, nil
+}
+
+func compileNamespaced(u *unstructured.Unstructured) (Enforcement, []policy.Policy, error) {
+	var obj NamespacedAppAdmissionPolicy
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &obj)
+	if err != nil {
+		return "", nil, microerror.Maskf(invalidPolicyError, "%s", err)
+	}
+
+	p := policy.Policy{
+		Name:       fmt.Sprintf("%s/%s", obj.Namespace, obj.Name),
+		Namespaces: []string{"exact:" + obj.Namespace},
+
+		Catalogs:         obj.Spec.Catalogs,
+		Apps:             obj.Spec.Apps,
+		TargetNamespaces: obj.Spec.TargetNamespaces,
+		NamespaceConfig:  obj.Spec.NamespaceConfig,
+		Options:          obj.Spec.Options,
+	}
+
+	return obj.Spec.Enforcement, []policy.Policy{p}, nil
+}
+
+func key(obj interface{}) string {
+	k, _ := cache.MetaNamespaceKeyFunc(obj)
+	return k
+}
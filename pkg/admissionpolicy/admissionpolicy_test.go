@@ -0,0 +1,211 @@
+package admissionpolicy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	authv1 "k8s.io/api/authentication/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/policy"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+func Test_Controller_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	objects := []runtime.Object{
+		newPolicy(ClusterResource, "", "enforced", map[string]interface{}{
+			"blacklist": map[string]interface{}{
+				"apps":     []interface{}{"kiam"},
+				"catalogs": []interface{}{"giantswarm"},
+			},
+		}),
+		newPolicy(ClusterResource, "", "audited", map[string]interface{}{
+			"enforcement": "Audit",
+			"blacklist": map[string]interface{}{
+				"apps":     []interface{}{"vault"},
+				"catalogs": []interface{}{"giantswarm"},
+			},
+		}),
+		newPolicy(ClusterResource, "", "broken", map[string]interface{}{
+			"blacklist": map[string]interface{}{
+				"apps": []interface{}{"regex:("},
+			},
+		}),
+		newPolicy(NamespacedResource, "demo0", "restricted", map[string]interface{}{
+			"apps": map[string]interface{}{
+				"denied": []interface{}{"nginx"},
+			},
+		}),
+	}
+
+	scheme := runtime.NewScheme()
+	dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		ClusterResource:    "AppAdmissionPolicyList",
+		NamespacedResource: "NamespacedAppAdmissionPolicyList",
+	}, objects...)
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		DynClient: dynClient,
+		K8sClient: clientgofake.NewClientset(),
+	})
+
+	newInspector := func() *secins.Inspector {
+		ins, err := secins.New(secins.Config{Logger: microloggertest.New()})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		return ins
+	}
+	newEvaluator := func() *policy.Evaluator {
+		e, err := policy.New(policy.Config{K8sClient: k8sClient, Logger: microloggertest.New()})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		return e
+	}
+
+	inspector := newInspector()
+	auditInspector := newInspector()
+	policyEvaluator := newEvaluator()
+	auditPolicyEvaluator := newEvaluator()
+
+	c, err := New(Config{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+
+		Inspector:            inspector,
+		PolicyEvaluator:      policyEvaluator,
+		AuditInspector:       auditInspector,
+		AuditPolicyEvaluator: auditPolicyEvaluator,
+
+		Namespaced: true,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = c.Run(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	userInfo := authv1.UserInfo{Username: "jane"}
+
+	tests := []struct {
+		name        string
+		inspector   *secins.Inspector
+		app         string
+		wantBlocked bool
+	}{
+		{name: "case 0: enforced policy rejects", inspector: inspector, app: "kiam", wantBlocked: true},
+		{name: "case 1: audited policy is not enforced", inspector: inspector, app: "vault"},
+		{name: "case 2: audit inspector holds enforced policies", inspector: auditInspector, app: "kiam", wantBlocked: true},
+		{name: "case 3: audit inspector holds audited policies", inspector: auditInspector, app: "vault", wantBlocked: true},
+		{name: "case 4: invalid policy is ignored", inspector: auditInspector, app: "("},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.inspector.Inspect(ctx, newApp("demo0", tc.app), userInfo)
+			if tc.wantBlocked && !secins.IsSecurityViolationError(err) {
+				t.Fatalf("error == %#v, want security violation", err)
+			}
+			if !tc.wantBlocked && err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+		})
+	}
+
+	err = policyEvaluator.Evaluate(ctx, newApp("demo0", "nginx"))
+	if !policy.IsPolicyViolation(err) {
+		t.Fatalf("error == %#v, want policy violation", err)
+	}
+	err = policyEvaluator.Evaluate(ctx, newApp("demo1", "nginx"))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	conditions := map[string]struct {
+		resource schema.GroupVersionResource
+		name     string
+		valid    metav1.ConditionStatus
+		reason   string
+	}{
+		"enforced":   {resource: ClusterResource, name: "enforced", valid: metav1.ConditionTrue, reason: reasonEnforced},
+		"audited":    {resource: ClusterResource, name: "audited", valid: metav1.ConditionTrue, reason: reasonAudited},
+		"broken":     {resource: ClusterResource, name: "broken", valid: metav1.ConditionFalse, reason: reasonInvalid},
+		"restricted": {resource: NamespacedResource, name: "restricted", valid: metav1.ConditionTrue, reason: reasonEnforced},
+	}
+
+	for name, want := range conditions {
+		namespace := ""
+		if want.resource == NamespacedResource {
+			namespace = "demo0"
+		}
+
+		u, err := dynClient.Resource(want.resource).Namespace(namespace).Get(ctx, want.name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		var status Status
+		s, _ := u.Object["status"].(map[string]interface{})
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(s, &status)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		valid := apimeta.FindStatusCondition(status.Conditions, ConditionValid)
+		if valid == nil || valid.Status != want.valid {
+			t.Fatalf("%s: Valid condition == %#v, want status %#q", name, valid, want.valid)
+		}
+		active := apimeta.FindStatusCondition(status.Conditions, ConditionActive)
+		if active == nil || active.Reason != want.reason {
+			t.Fatalf("%s: Active condition == %#v, want reason %#q", name, active, want.reason)
+		}
+	}
+}
+
+func newApp(namespace, name string) v1alpha1.App {
+	return v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: v1alpha1.AppSpec{
+			Catalog: "giantswarm",
+			Name:    name,
+		},
+	}
+}
+
+func newPolicy(r schema.GroupVersionResource, namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	kind := "AppAdmissionPolicy"
+	if r == NamespacedResource {
+		kind = "NamespacedAppAdmissionPolicy"
+	}
+
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": spec,
+	}}
+	u.SetAPIVersion(Group + "/" + Version)
+	u.SetKind(kind)
+	u.SetName(name)
+	u.SetNamespace(namespace)
+	u.SetGeneration(1)
+
+	return u
+}
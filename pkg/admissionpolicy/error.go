@@ -0,0 +1,23 @@
+package admissionpolicy
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidPolicyError = &microerror.Error{
+	Kind: "invalidPolicyError",
+}
+
+// IsInvalidPolicy asserts invalidPolicyError.
+func IsInvalidPolicy(err error) bool {
+	return microerror.Cause(err) == invalidPolicyError
+}
@@ -0,0 +1,99 @@
+package admissionpolicy
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/policy"
+)
+
+const (
+	Group   = "admission.giantswarm.io"
+	Version = "v1alpha1"
+)
+
+var (
+	// ClusterResource is the cluster scoped AppAdmissionPolicy resource.
+	ClusterResource = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "appadmissionpolicies"}
+	// NamespacedResource is the NamespacedAppAdmissionPolicy resource.
+	NamespacedResource = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "namespacedappadmissionpolicies"}
+)
+
+// Enforcement is the way violations of a policy are handled.
+type Enforcement string
+
+const (
+	// EnforcementEnforce rejects App CRs violating the policy.
+	EnforcementEnforce Enforcement = "Enforce"
+	// EnforcementAudit admits App CRs violating the policy, but logs the
+	// violation and counts it in a metric.
+	EnforcementAudit Enforcement = "Audit"
+)
+
+const (
+	// ConditionValid reports whether the policy compiles.
+	ConditionValid = "Valid"
+	// ConditionActive reports whether the policy is used for admission.
+	ConditionActive = "Active"
+)
+
+// AppAdmissionPolicy is a cluster scoped policy holding the same security
+// configuration as the command line flags, plus tenant policies.
+type AppAdmissionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AppAdmissionPolicySpec `json:"spec"`
+	Status Status                 `json:"status,omitempty"`
+}
+
+type AppAdmissionPolicySpec struct {
+	Enforcement Enforcement `json:"enforcement,omitempty"`
+
+	// Whitelist holds matcher rules for users and groups whose App CRs are
+	// not inspected.
+	Whitelist Whitelist `json:"whitelist,omitempty"`
+	// Blacklist holds matcher rules for apps, catalogs and namespaces which
+	// must not be used by App CRs.
+	Blacklist Blacklist `json:"blacklist,omitempty"`
+	// Tenants holds catalog and app restrictions of tenants, see the
+	// policy package.
+	Tenants []policy.Policy `json:"tenants,omitempty"`
+}
+
+type Whitelist struct {
+	Groups []string `json:"groups,omitempty"`
+	Users  []string `json:"users,omitempty"`
+}
+
+type Blacklist struct {
+	Apps       []string `json:"apps,omitempty"`
+	Catalogs   []string `json:"catalogs,omitempty"`
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// NamespacedAppAdmissionPolicy restricts App CRs in its own namespace. It
+// can only add restrictions, so it may be delegated to the teams owning the
+// namespace.
+type NamespacedAppAdmissionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NamespacedAppAdmissionPolicySpec `json:"spec"`
+	Status Status                           `json:"status,omitempty"`
+}
+
+type NamespacedAppAdmissionPolicySpec struct {
+	Enforcement Enforcement `json:"enforcement,omitempty"`
+
+	Catalogs         []policy.Catalog       `json:"catalogs,omitempty"`
+	Apps             policy.Names           `json:"apps,omitempty"`
+	TargetNamespaces policy.Names           `json:"targetNamespaces,omitempty"`
+	NamespaceConfig  policy.NamespaceConfig `json:"namespaceConfig,omitempty"`
+	Options          policy.Options         `json:"options,omitempty"`
+}
+
+type Status struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
@@ -34,3 +34,30 @@ func IsParsingFailed(err error) bool {
 var clusterAppVersionNotFound = &microerror.Error{
 	Kind: "clusterAppVersionNotFoundError",
 }
+
+var managedFieldChangedError = &microerror.Error{
+	Kind: "managedFieldChangedError",
+}
+
+// IsManagedFieldChanged asserts managedFieldChangedError.
+func IsManagedFieldChanged(err error) bool {
+	return microerror.Cause(err) == managedFieldChangedError
+}
+
+var invalidExtraConfigsError = &microerror.Error{
+	Kind: "invalidExtraConfigsError",
+}
+
+// IsInvalidExtraConfigs asserts invalidExtraConfigsError.
+func IsInvalidExtraConfigs(err error) bool {
+	return microerror.Cause(err) == invalidExtraConfigsError
+}
+
+var protectedMetadataChangedError = &microerror.Error{
+	Kind: "protectedMetadataChangedError",
+}
+
+// IsProtectedMetadataChanged asserts protectedMetadataChangedError.
+func IsProtectedMetadataChanged(err error) bool {
+	return microerror.Cause(err) == protectedMetadataChangedError
+}
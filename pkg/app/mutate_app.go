@@ -28,6 +28,10 @@ type MutatorConfig struct {
 	Logger        micrologger.Logger
 	Provider      string
 	ConfigPatches []config.ConfigPatch
+
+	// DryRun makes the mutator compute patches without creating or
+	// updating any objects they refer to, e.g. PSP removal ConfigMaps.
+	DryRun bool
 }
 
 type Mutator struct {
@@ -36,6 +40,7 @@ type Mutator struct {
 	// provider & configPatches are required by mutateConfigForPSPRemoval()
 	provider      string
 	configPatches []config.ConfigPatch
+	dryRun        bool
 	valuesService *values.Values
 }
 
@@ -61,6 +66,7 @@ func NewMutator(config MutatorConfig) (*Mutator, error) {
 		logger:        config.Logger,
 		provider:      config.Provider,
 		configPatches: config.ConfigPatches,
+		dryRun:        config.DryRun,
 		valuesService: valuesService,
 	}
 
@@ -106,6 +112,10 @@ func (m *Mutator) Mutate(request *admissionv1.AdmissionRequest) ([]mutator.Patch
 		return nil, microerror.Mask(err)
 	}
 
+	// The creator is recorded regardless of the version label, as it is
+	// not used by app-operator.
+	result = append(result, mutateCreator(*appOldCR, *appNewCR, request.Operation, request.UserInfo)...)
+
 	m.logger.Debugf(ctx, "applying %d patches to app %#q in namespace %#q", len(result), appNewCR.Name, appNewCR.Namespace)
 
 	return result, nil
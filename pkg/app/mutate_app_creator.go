@@ -0,0 +1,67 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	authv1 "k8s.io/api/authentication/v1"
+
+	"github.com/giantswarm/app-admission-controller/v2/pkg/mutator"
+)
+
+const (
+	// CreatorAnnotation and CreatorGroupsAnnotation hold the user creating
+	// the App CR and its groups, separated by commas.
+	CreatorAnnotation       = "app-admission-controller.giantswarm.io/creator"
+	CreatorGroupsAnnotation = "app-admission-controller.giantswarm.io/creator-groups"
+)
+
+var creatorAnnotations = []string{CreatorAnnotation, CreatorGroupsAnnotation}
+
+// mutateCreator stamps the user creating the App CR and its groups on
+// create. On updates the annotations are restored to their current values,
+// or removed when the App CR did not have them, so that they can't be
+// forged.
+func mutateCreator(oldApp, app v1alpha1.App, operation admissionv1.Operation, userInfo authv1.UserInfo) []mutator.PatchOperation {
+	desired := map[string]string{}
+	switch operation {
+	case admissionv1.Create:
+		if userInfo.Username == "" {
+			return nil
+		}
+		desired[CreatorAnnotation] = userInfo.Username
+		desired[CreatorGroupsAnnotation] = strings.Join(userInfo.Groups, ",")
+	case admissionv1.Update:
+		for _, k := range creatorAnnotations {
+			if v, ok := oldApp.Annotations[k]; ok {
+				desired[k] = v
+			}
+		}
+	default:
+		return nil
+	}
+
+	var result []mutator.PatchOperation
+	for _, k := range creatorAnnotations {
+		path := fmt.Sprintf("/metadata/annotations/%s", replaceToEscape(k))
+
+		v, ok := desired[k]
+		current, set := app.Annotations[k]
+		switch {
+		case ok && (!set || current != v):
+			result = append(result, mutator.PatchAdd(path, v))
+		case !ok && set:
+			result = append(result, mutator.PatchRemove(path))
+		}
+	}
+
+	// Annotations can't be added to null JSON objects, so the whole map is
+	// set for App CRs without annotations, which can only need additions.
+	if len(result) > 0 && len(app.Annotations) == 0 {
+		return []mutator.PatchOperation{mutator.PatchAdd("/metadata/annotations", desired)}
+	}
+
+	return result
+}
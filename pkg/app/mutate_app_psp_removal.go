@@ -208,6 +208,11 @@ func (m *Mutator) mutateConfigForPSPRemoval(ctx context.Context, app v1alpha1.Ap
 // ensureConfigMap tries to create given ConfigMap. If it already exists, it
 // updates the CM to ensure content consistency.
 func (m *Mutator) ensureConfigMap(ctx context.Context, namespace, name, values string) error {
+	if m.dryRun {
+		m.logger.Debugf(ctx, "Skipping configmap '%s' in '%s' namespace in dry run mode\n", name, namespace)
+		return nil
+	}
+
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
@@ -14,6 +14,7 @@ import (
 	release "github.com/giantswarm/releases/sdk/api/v1alpha1"
 	"github.com/google/go-cmp/cmp"
 	admissionv1 "k8s.io/api/admission/v1"
+	authv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -1353,6 +1354,96 @@ func Test_MutateApp(t *testing.T) {
 	}
 }
 
+func Test_mutateCreator(t *testing.T) {
+	withAnnotations := func(annotations map[string]string) v1alpha1.App {
+		app := *newTestApp("hello-world", "org-acme", "0.0.0")
+		app.Annotations = annotations
+		return app
+	}
+	creator := map[string]string{
+		CreatorAnnotation:       "jane",
+		CreatorGroupsAnnotation: "developers,admins",
+	}
+
+	tests := []struct {
+		name            string
+		oldObj          v1alpha1.App
+		obj             v1alpha1.App
+		operation       admissionv1.Operation
+		expectedPatches []mutator.PatchOperation
+	}{
+		{
+			name:      "case 0: creator is stamped on create",
+			obj:       withAnnotations(map[string]string{"some": "annotation"}),
+			operation: admissionv1.Create,
+			expectedPatches: []mutator.PatchOperation{
+				mutator.PatchAdd("/metadata/annotations/app-admission-controller.giantswarm.io~1creator", "jane"),
+				mutator.PatchAdd("/metadata/annotations/app-admission-controller.giantswarm.io~1creator-groups", "developers,admins"),
+			},
+		},
+		{
+			name:      "case 1: creator is stamped on create of app without annotations",
+			obj:       withAnnotations(nil),
+			operation: admissionv1.Create,
+			expectedPatches: []mutator.PatchOperation{
+				mutator.PatchAdd("/metadata/annotations", creator),
+			},
+		},
+		{
+			name: "case 2: forged creator is overwritten on create",
+			obj: withAnnotations(map[string]string{
+				CreatorAnnotation:       "john",
+				CreatorGroupsAnnotation: "developers,admins",
+			}),
+			operation: admissionv1.Create,
+			expectedPatches: []mutator.PatchOperation{
+				mutator.PatchAdd("/metadata/annotations/app-admission-controller.giantswarm.io~1creator", "jane"),
+			},
+		},
+		{
+			name:      "case 3: unchanged creator is kept on update",
+			oldObj:    withAnnotations(creator),
+			obj:       withAnnotations(creator),
+			operation: admissionv1.Update,
+		},
+		{
+			name:   "case 4: changed creator is restored on update",
+			oldObj: withAnnotations(creator),
+			obj: withAnnotations(map[string]string{
+				CreatorAnnotation: "john",
+			}),
+			operation: admissionv1.Update,
+			expectedPatches: []mutator.PatchOperation{
+				mutator.PatchAdd("/metadata/annotations/app-admission-controller.giantswarm.io~1creator", "jane"),
+				mutator.PatchAdd("/metadata/annotations/app-admission-controller.giantswarm.io~1creator-groups", "developers,admins"),
+			},
+		},
+		{
+			name:   "case 5: creator added on update is removed",
+			oldObj: withAnnotations(map[string]string{"some": "annotation"}),
+			obj: withAnnotations(map[string]string{
+				"some":            "annotation",
+				CreatorAnnotation: "john",
+			}),
+			operation: admissionv1.Update,
+			expectedPatches: []mutator.PatchOperation{
+				mutator.PatchRemove("/metadata/annotations/app-admission-controller.giantswarm.io~1creator"),
+			},
+		},
+	}
+
+	userInfo := authv1.UserInfo{Username: "jane", Groups: []string{"developers", "admins"}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			patches := mutateCreator(tc.oldObj, tc.obj, tc.operation, userInfo)
+			if !reflect.DeepEqual(patches, tc.expectedPatches) {
+				t.Fatalf("want matching patches \n %s", cmp.Diff(patches, tc.expectedPatches))
+			}
+		})
+	}
+}
+
 func newTestApp(name, namespace, versionLabel string) *v1alpha1.App {
 	return &v1alpha1.App{
 		ObjectMeta: metav1.ObjectMeta{
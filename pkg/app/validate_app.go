@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
@@ -14,7 +15,16 @@ import (
 	"github.com/giantswarm/micrologger"
 	admissionv1 "k8s.io/api/admission/v1"
 
+	"github.com/giantswarm/app-admission-controller/v2/internal/expression"
 	"github.com/giantswarm/app-admission-controller/v2/internal/recorder"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/access"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/breakglass"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/deletion"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/ownership"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/policy"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/targeting"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/metrics"
 	"github.com/giantswarm/app-admission-controller/v2/pkg/validator"
 
 	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
@@ -33,13 +43,71 @@ type ValidatorConfig struct {
 
 	Provider  string
 	Inspector *secins.Inspector
+
+	// ProtectedFields are the classes of fields managed by the mutator
+	// which must not be removed or changed on updates, see ManagedFields.
+	ProtectedFields []string
+	// ProtectedFinalizers and ProtectedLabels must not be added, removed or
+	// changed on updates, except by whitelisted service accounts.
+	ProtectedFinalizers []string
+	ProtectedLabels     []string
+
+	// BreakGlassVerifier optionally verifies break-glass tokens of App CRs,
+	// which bypass violations of the configured rules.
+	BreakGlassVerifier *breakglass.Verifier
+	// AccessReviewer optionally reviews whether users may read the objects
+	// referenced by the App CRs they submit.
+	AccessReviewer *access.Reviewer
+	// TargetingChecker optionally checks that App CRs in organization
+	// namespaces only target clusters of the same organization.
+	TargetingChecker *targeting.Checker
+	// OwnershipChecker optionally checks that changes of App CRs owned by a
+	// team are made by its members.
+	OwnershipChecker *ownership.Checker
+	// DeletionGuard optionally denies unconfirmed deletions of critical App
+	// CRs. DELETE requests are only sent to the webhook when it is set.
+	DeletionGuard *deletion.Guard
+	// PolicyEvaluator optionally evaluates App CRs against the catalog and
+	// app policies of their namespace.
+	PolicyEvaluator *policy.Evaluator
+	// Enforcer decides how violations of rules are handled. All rules are
+	// enforced when empty.
+	Enforcer *enforcement.Enforcer
+	// RuleEvaluator optionally evaluates App CRs against CEL validation
+	// rules.
+	RuleEvaluator *expression.Evaluator
+	// AuditInspector and AuditPolicyEvaluator optionally hold policies in
+	// the audit mode, whose violations are only reported.
+	AuditInspector       *secins.Inspector
+	AuditPolicyEvaluator *policy.Evaluator
+
+	// Silent disables the metrics of break-glass rejections and policy
+	// audit violations, for validators whose decisions are no admissions,
+	// e.g. of candidates. Enforcers, break-glass verifiers and rule
+	// evaluators have their own options.
+	Silent bool
 }
 
 type Validator struct {
-	appValidator *validation.Validator
-	event        recorder.Interface
-	logger       micrologger.Logger
-	inspector    *secins.Inspector
+	appValidator         *validation.Validator
+	accessReviewer       *access.Reviewer
+	breakGlassVerifier   *breakglass.Verifier
+	deletionGuard        *deletion.Guard
+	auditInspector       *secins.Inspector
+	auditPolicyEvaluator *policy.Evaluator
+	enforcer             *enforcement.Enforcer
+	event                recorder.Interface
+	k8sClient            k8sclient.Interface
+	logger               micrologger.Logger
+	inspector            *secins.Inspector
+	ownershipChecker     *ownership.Checker
+	protectedFields      []string
+	protectedFinalizers  []string
+	protectedLabels      []string
+	policyEvaluator      *policy.Evaluator
+	ruleEvaluator        *expression.Evaluator
+	silent               bool
+	targetingChecker     *targeting.Checker
 }
 
 func NewValidator(config ValidatorConfig) (*Validator, error) {
@@ -60,9 +128,22 @@ func NewValidator(config ValidatorConfig) (*Validator, error) {
 	if config.Inspector == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.SecurityInformer must not be empty", config)
 	}
+	for _, f := range config.ProtectedFields {
+		if !slices.Contains(ManagedFields, f) {
+			return nil, microerror.Maskf(invalidConfigError, "%T.ProtectedFields must only contain %v, got %#q", config, ManagedFields, f)
+		}
+	}
 
 	var err error
 
+	enforcer := config.Enforcer
+	if enforcer == nil {
+		enforcer, err = enforcement.New(enforcement.Config{Logger: config.Logger})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var appValidator *validation.Validator
 	{
 		c := validation.Config{
@@ -80,10 +161,25 @@ func NewValidator(config ValidatorConfig) (*Validator, error) {
 	}
 
 	v := &Validator{
-		appValidator: appValidator,
-		event:        config.Event,
-		logger:       config.Logger,
-		inspector:    config.Inspector,
+		appValidator:         appValidator,
+		accessReviewer:       config.AccessReviewer,
+		breakGlassVerifier:   config.BreakGlassVerifier,
+		deletionGuard:        config.DeletionGuard,
+		auditInspector:       config.AuditInspector,
+		auditPolicyEvaluator: config.AuditPolicyEvaluator,
+		enforcer:             enforcer,
+		event:                config.Event,
+		k8sClient:            config.K8sClient,
+		logger:               config.Logger,
+		inspector:            config.Inspector,
+		ownershipChecker:     config.OwnershipChecker,
+		protectedFields:      config.ProtectedFields,
+		protectedFinalizers:  config.ProtectedFinalizers,
+		protectedLabels:      config.ProtectedLabels,
+		policyEvaluator:      config.PolicyEvaluator,
+		ruleEvaluator:        config.RuleEvaluator,
+		silent:               config.Silent,
+		targetingChecker:     config.TargetingChecker,
 	}
 
 	return v, nil
@@ -102,19 +198,204 @@ func (v *Validator) Resource() string {
 }
 
 func (v *Validator) Validate(request *admissionv1.AdmissionRequest) (bool, error) {
+	allowed, _, err := v.ValidateWithWarnings(request)
+	return allowed, err
+}
+
+// ValidateWithWarnings validates the request like Validate, and also
+// returns the admission warnings of violated rules in the warn mode.
+func (v *Validator) ValidateWithWarnings(request *admissionv1.AdmissionRequest) (bool, []string, error) {
+	allowed, warnings, _, err := v.ValidateWithAudit(request)
+	return allowed, warnings, err
+}
+
+// ValidateWithAudit validates the request like ValidateWithWarnings, and
+// also returns the audit annotations of rules bypassed with a break-glass
+// token.
+func (v *Validator) ValidateWithAudit(request *admissionv1.AdmissionRequest) (bool, []string, map[string]string, error) {
 	ctx := context.Background()
 
+	if request.Operation == admissionv1.Delete {
+		allowed, warnings, err := v.validateDelete(ctx, request)
+		return allowed, warnings, nil, err
+	}
+
 	var app v1alpha1.App
 
 	if _, _, err := validator.Deserializer.Decode(request.Object.Raw, nil, &app); err != nil {
-		return false, microerror.Maskf(parsingFailedError, "unable to parse app: %#v", err)
+		return false, nil, nil, microerror.Maskf(parsingFailedError, "unable to parse app: %#v", err)
 	}
 
 	v.logger.Debugf(ctx, "validating app %#q in namespace %#q", app.Name, app.Namespace)
 
+	// A valid break-glass token bypasses violations of the configured
+	// rules, while an invalid one rejects the app right away. Tokens of App
+	// CRs being deleted are not verified, so that they can't block the
+	// removal of finalizers.
+	var token *breakglass.Token
+	if v.breakGlassVerifier != nil && app.DeletionTimestamp.IsZero() {
+		currentApp, err := decodeCurrentApp(request)
+		if err != nil {
+			return false, nil, nil, microerror.Mask(err)
+		}
+
+		token, err = v.breakGlassVerifier.Verify(ctx, app, currentApp)
+		if breakglass.IsInvalidToken(err) {
+			v.logger.Errorf(ctx, err, "rejected app %#q in namespace %#q due to its break-glass token", app.Name, app.Namespace)
+			if !v.silent {
+				metrics.BreakGlassRejections.WithLabelValues(app.Namespace).Inc()
+			}
+			return false, nil, nil, microerror.Mask(err)
+		} else if err != nil {
+			return false, nil, nil, microerror.Mask(err)
+		}
+	}
+
+	var bypassed []string
+	bypass := func(err error) error {
+		if token == nil {
+			return err
+		}
+
+		b, err := v.breakGlassVerifier.Bypass(ctx, app, token, err)
+		bypassed = append(bypassed, b...)
+		return err
+	}
+
+	allowed, warnings, err := v.validate(ctx, request, app, bypass)
+	if err != nil || len(bypassed) == 0 {
+		return allowed, warnings, nil, err
+	}
+
+	v.event.EmitWarning(ctx, &app, "BreakGlass", "rules %s bypassed with break-glass token of key %#q: %s", strings.Join(bypassed, ", "), token.Key, token.Reason)
+
+	auditAnnotations := map[string]string{
+		"break-glass-key":    token.Key,
+		"break-glass-reason": token.Reason,
+		"break-glass-rules":  strings.Join(bypassed, ","),
+	}
+
+	return allowed, warnings, auditAnnotations, nil
+}
+
+// validate runs the checks of the validator against the app. Violations are
+// passed to bypass before their enforcement modes are applied.
+func (v *Validator) validate(ctx context.Context, request *admissionv1.AdmissionRequest, app v1alpha1.App, bypass func(error) error) (bool, []string, error) {
+	// Violations of rules are handled in the enforcement mode of the rule,
+	// and only rejected in the enforce mode.
+	var warnings []string
+	decide := func(err error) error {
+		w, err := v.enforcer.Decide(ctx, app, request.UserInfo, bypass(err))
+		warnings = append(warnings, w...)
+		return err
+	}
+
+	// Protected finalizers and labels tie App CRs to the operator
+	// reconciling them, so only whitelisted service accounts, e.g. of
+	// app-operator, may change them. Whitelisted humans are not trusted
+	// with them, they may use break-glass tokens instead. They are checked
+	// for App CRs being deleted as well, as removing the finalizer of
+	// app-operator then orphans the Helm release.
+	if (len(v.protectedFinalizers) > 0 || len(v.protectedLabels) > 0) && request.UserInfo.Username != "" && !(isServiceAccount(request.UserInfo) && v.inspector.IsWhitelisted(ctx, request.UserInfo)) {
+		currentApp, err := decodeCurrentApp(request)
+		if err != nil {
+			return false, nil, microerror.Mask(err)
+		}
+
+		if currentApp != nil {
+			err = decide(enforcement.WithViolation(enforcement.RuleProtectedMetadata, checkProtectedMetadata(v.protectedFinalizers, v.protectedLabels, app, *currentApp), IsProtectedMetadataChanged))
+			if err != nil {
+				v.logger.Errorf(ctx, err, "rejected update of app %#q in namespace %#q changing protected metadata", app.Name, app.Namespace)
+				return false, nil, microerror.Mask(err)
+			}
+		}
+	}
+
 	if request.Operation == admissionv1.Update && !app.DeletionTimestamp.IsZero() {
 		v.logger.Debugf(ctx, "skipping validation for UPDATE operation of app %#q in namespace %#q with non-zero deletion timestamp", app.Name, app.Namespace)
-		return true, nil
+		return true, warnings, nil
+	}
+
+	err := v.inspect(ctx, request, app, v.inspector, v.policyEvaluator, decide)
+	if err != nil {
+		return false, nil, microerror.Mask(err)
+	}
+
+	// Policies in the audit mode are only evaluated once the enforced ones
+	// admitted the app, and their violations are only reported.
+	if v.auditInspector != nil {
+		err := v.inspect(ctx, request, app, v.auditInspector, v.auditPolicyEvaluator, func(err error) error { return err })
+		if err != nil && !v.silent {
+			v.logger.Errorf(ctx, err, "admitting app %#q in namespace %#q violating policies in the audit mode", app.Name, app.Namespace)
+			metrics.PolicyAuditViolations.WithLabelValues(app.Namespace).Inc()
+		}
+	}
+
+	// Owner teams apply to all apps, regardless of the version label.
+	// Requests without a user, e.g. of audits, are not checked.
+	if v.ownershipChecker != nil && request.UserInfo.Username != "" {
+		currentApp, err := decodeCurrentApp(request)
+		if err != nil {
+			return false, nil, microerror.Mask(err)
+		}
+
+		err = decide(enforcement.WithViolation(enforcement.RuleOwnerTeam, v.ownershipChecker.Check(ctx, app, currentApp, request.UserInfo), ownership.IsNotOwner))
+		if err != nil {
+			v.logger.Errorf(ctx, err, "rejected app %#q in namespace %#q due to its owner team", app.Name, app.Namespace)
+			return false, nil, microerror.Mask(err)
+		}
+	}
+
+	// Fields managed by the mutator are protected for all apps, except
+	// from whitelisted users, e.g. to retarget apps.
+	if len(v.protectedFields) > 0 && request.UserInfo.Username != "" && !v.inspector.IsWhitelisted(ctx, request.UserInfo) {
+		currentApp, err := decodeCurrentApp(request)
+		if err != nil {
+			return false, nil, microerror.Mask(err)
+		}
+
+		if currentApp != nil {
+			err = decide(enforcement.WithViolation(enforcement.RuleManagedFields, checkManagedFields(v.protectedFields, app, *currentApp), IsManagedFieldChanged))
+			if err != nil {
+				v.logger.Errorf(ctx, err, "rejected update of app %#q in namespace %#q changing managed fields", app.Name, app.Namespace)
+				return false, nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	// app-operator reads the referenced objects with its own privileges,
+	// so users must be allowed to read them themselves. This applies to all
+	// apps, regardless of the version label. Requests without a user, e.g.
+	// of audits, and requests of whitelisted users are not reviewed.
+	var referencesReviewed bool
+	if v.accessReviewer != nil && request.UserInfo.Username != "" && !v.inspector.IsWhitelisted(ctx, request.UserInfo) {
+		currentApp, err := decodeCurrentApp(request)
+		if err != nil {
+			return false, nil, microerror.Mask(err)
+		}
+
+		err = v.accessReviewer.Review(ctx, app, currentApp, request.UserInfo)
+		referencesReviewed = err == nil
+
+		err = decide(enforcement.WithViolation(enforcement.RuleReferenceAccess, err, access.IsAccessDenied))
+		if err != nil {
+			v.logger.Errorf(ctx, err, "rejected app %#q in namespace %#q due to inaccessible references", app.Name, app.Namespace)
+			return false, nil, microerror.Mask(err)
+		}
+	}
+
+	// Validation rules apply to all apps, regardless of the version label,
+	// like policies, so that they can not be evaded by changing the label.
+	if v.ruleEvaluator != nil {
+		currentApp, err := decodeCurrentApp(request)
+		if err != nil {
+			return false, nil, microerror.Mask(err)
+		}
+
+		err = decide(v.ruleEvaluator.Evaluate(ctx, string(request.Operation), app, currentApp, request.UserInfo))
+		if err != nil {
+			return false, nil, microerror.Mask(err)
+		}
 	}
 
 	isManagedInOrg := !key.InCluster(app) && key.IsInOrgNamespace(app)
@@ -122,7 +403,7 @@ func (v *Validator) Validate(request *admissionv1.AdmissionRequest) (bool, error
 	ver, err := semver.NewVersion(key.VersionLabel(app))
 	if !isManagedInOrg && err != nil {
 		v.logger.Debugf(ctx, "skipping validation of app %#q in namespace %#q due to version label %#q", app.Name, app.Namespace, key.VersionLabel(app))
-		return true, nil
+		return true, warnings, nil
 	}
 
 	// If the app CR does not have the unique version and is < 3.0.0 we skip
@@ -130,7 +411,7 @@ func (v *Validator) Validate(request *admissionv1.AdmissionRequest) (bool, error
 	// enabled for existing platform releases.
 	if !isManagedInOrg && key.VersionLabel(app) != uniqueAppCRVersion && ver.Major() < 3 {
 		v.logger.Debugf(ctx, "skipping validation of app %#q in namespace %#q due to version label %#q", app.Name, app.Namespace, key.VersionLabel(app))
-		return true, nil
+		return true, warnings, nil
 	}
 
 	// Let's log users names and groups membership in case we need to
@@ -143,20 +424,51 @@ func (v *Validator) Validate(request *admissionv1.AdmissionRequest) (bool, error
 		strings.Join(request.UserInfo.Groups, ","),
 	)
 
-	// When creating App CR for unique App Operator run extra check to find out:
-	// - illegal references in config or userConfig
-	// - blacklisted app being requested
-	if key.VersionLabel(app) == uniqueAppCRVersion {
-		err := v.inspector.Inspect(ctx, app, request.UserInfo)
+	if v.targetingChecker != nil {
+		currentApp, err := decodeCurrentApp(request)
+		if err != nil {
+			return false, nil, microerror.Mask(err)
+		}
+
+		err = decide(enforcement.WithViolation(enforcement.RuleClusterTargeting, v.targetingChecker.Check(ctx, app, currentApp, request.UserInfo), targeting.IsCrossOrganization, targeting.IsRetargeting))
 		if err != nil {
-			return false, microerror.Mask(err)
+			v.logger.Errorf(ctx, err, "rejected app %#q in namespace %#q due to its target cluster", app.Name, app.Namespace)
+			return false, nil, microerror.Mask(err)
 		}
 	}
 
 	appAllowed, err := v.appValidator.ValidateApp(ctx, app)
 	if err != nil {
-		v.logger.Errorf(ctx, err, "rejected app %#q in namespace %#q", app.Name, app.Namespace)
-		return false, microerror.Mask(err)
+		err = decide(enforcement.WithViolation(enforcement.RuleAppValidation, err, isAppValidationViolation))
+		if err != nil {
+			v.logger.Errorf(ctx, err, "rejected app %#q in namespace %#q", app.Name, app.Namespace)
+			return false, nil, microerror.Mask(err)
+		}
+
+		// The app is admitted in spite of failing the validation of a
+		// non-enforced rule.
+		appAllowed = true
+	}
+
+	// The structure of .spec.extraConfigs is validated once the built-in
+	// validation admitted the app. Entries referencing objects which do not
+	// exist only cause warnings, as they may be created later. Objects in
+	// other namespaces are only looked up when the user passed the review
+	// of the references, so that the warnings do not disclose objects the
+	// user may not read.
+	{
+		currentApp, err := decodeCurrentApp(request)
+		if err != nil {
+			return false, nil, microerror.Mask(err)
+		}
+
+		w, err := v.validateExtraConfigs(ctx, app, currentApp, referencesReviewed)
+		err = decide(enforcement.WithViolation(enforcement.RuleExtraConfigs, err, IsInvalidExtraConfigs))
+		if err != nil {
+			v.logger.Errorf(ctx, err, "rejected app %#q in namespace %#q due to its extraConfigs", app.Name, app.Namespace)
+			return false, nil, microerror.Mask(err)
+		}
+		warnings = append(warnings, w...)
 	}
 
 	var currentApp v1alpha1.App
@@ -165,13 +477,14 @@ func (v *Validator) Validate(request *admissionv1.AdmissionRequest) (bool, error
 		if _, _, err := validator.Deserializer.Decode(request.OldObject.Raw, nil, &currentApp); err != nil {
 			// We can't compare with the current app. So we allow
 			// the update but still log the error.
-			return true, microerror.Maskf(parsingFailedError, "unable to parse current app: %#v", err)
+			return true, warnings, microerror.Maskf(parsingFailedError, "unable to parse current app: %#v", err)
 		}
 
 		_, err := v.appValidator.ValidateAppUpdate(ctx, app, currentApp)
+		err = decide(enforcement.WithViolation(enforcement.RuleAppUpdateValidation, err, isAppValidationViolation))
 		if err != nil {
 			v.logger.Errorf(ctx, err, "rejected update of app %#q in namespace %#q", app.Name, app.Namespace)
-			return false, microerror.Mask(err)
+			return false, nil, microerror.Mask(err)
 		}
 	}
 
@@ -183,7 +496,94 @@ func (v *Validator) Validate(request *admissionv1.AdmissionRequest) (bool, error
 
 	v.logger.Debugf(ctx, "admitted app %#q in namespace %#q", app.Name, app.Namespace)
 
-	return appAllowed, nil
+	return appAllowed, warnings, nil
+}
+
+// inspect runs the security checks of the inspector and the policy
+// evaluator, which is optional. They run before the skipping logic of
+// Validate, as they apply to all apps. Violations are passed to decide,
+// which returns the ones to reject the app for.
+func (v *Validator) inspect(ctx context.Context, request *admissionv1.AdmissionRequest, app v1alpha1.App, inspector *secins.Inspector, policyEvaluator *policy.Evaluator, decide func(error) error) error {
+	// Setting or changing the fields making the app installed in the
+	// management cluster is checked before any of the skipping logic, as
+	// it depends on these very fields. Requests without a user, e.g. of
+	// audits, are not checked.
+	if request.UserInfo.Username != "" {
+		currentApp, err := decodeCurrentApp(request)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = decide(inspector.InspectPrivilegedFields(ctx, app, currentApp, request.UserInfo))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// Policies apply to all apps of their tenants, regardless of the version
+	// label. Whitelisted users are not restricted by them.
+	if policyEvaluator != nil && !inspector.IsWhitelisted(ctx, request.UserInfo) {
+		err := decide(policyEvaluator.Evaluate(ctx, app))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// When creating App CR for unique App Operator run extra check to find out:
+	// - illegal references in config or userConfig
+	// - blacklisted app being requested
+	if key.VersionLabel(app) == uniqueAppCRVersion {
+		err := decide(inspector.Inspect(ctx, app, request.UserInfo))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// isAppValidationViolation asserts the errors of the validation of the app
+// library which are violations, rather than failures to validate the app.
+func isAppValidationViolation(err error) bool {
+	return validation.IsValidationError(err) || validation.IsAppConfigMapNotFound(err) || validation.IsKubeConfigNotFound(err)
+}
+
+// validateDelete denies unconfirmed deletions of critical App CRs. The App
+// CR being deleted is only sent as the old object.
+func (v *Validator) validateDelete(ctx context.Context, request *admissionv1.AdmissionRequest) (bool, []string, error) {
+	if v.deletionGuard == nil {
+		return true, nil, nil
+	}
+
+	var app v1alpha1.App
+	if _, _, err := validator.Deserializer.Decode(request.OldObject.Raw, nil, &app); err != nil {
+		return false, nil, microerror.Maskf(parsingFailedError, "unable to parse app: %#v", err)
+	}
+
+	v.logger.Debugf(ctx, "validating deletion of app %#q in namespace %#q", app.Name, app.Namespace)
+
+	warnings, err := v.enforcer.Decide(ctx, app, request.UserInfo, enforcement.WithViolation(enforcement.RuleCriticalAppDeletion, v.deletionGuard.Check(ctx, app, request.UserInfo), deletion.IsCriticalAppDeletion))
+	if err != nil {
+		v.logger.Errorf(ctx, err, "rejected deletion of app %#q in namespace %#q", app.Name, app.Namespace)
+		return false, nil, microerror.Mask(err)
+	}
+
+	return true, warnings, nil
+}
+
+// decodeCurrentApp returns the App CR being updated, or nil for other
+// operations.
+func decodeCurrentApp(request *admissionv1.AdmissionRequest) (*v1alpha1.App, error) {
+	if request.Operation != admissionv1.Update {
+		return nil, nil
+	}
+
+	var currentApp v1alpha1.App
+	if _, _, err := validator.Deserializer.Decode(request.OldObject.Raw, nil, &currentApp); err != nil {
+		return nil, microerror.Maskf(parsingFailedError, "unable to parse current app: %#v", err)
+	}
+
+	return &currentApp, nil
 }
 
 func (v *Validator) emitEvents(ctx context.Context, request *admissionv1.AdmissionRequest, app v1alpha1.App) error {
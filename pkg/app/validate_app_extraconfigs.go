@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/app/v8/pkg/key"
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/giantswarm/app-admission-controller/v2/pkg/validator"
+)
+
+const (
+	extraConfigKindConfigMap = "configMap"
+	extraConfigKindSecret    = "secret"
+)
+
+var extraConfigKinds = []string{extraConfigKindConfigMap, extraConfigKindSecret}
+
+// validateExtraConfigs returns an invalidExtraConfigsError, with the field
+// errors as causes, when entries of .spec.extraConfigs have an unknown kind,
+// no name or namespace, a priority app-operator does not support or which is
+// reserved for the entries of the mutator, or list an object listed with
+// another priority already. Only entries which are not in the current App
+// CR are validated, so that existing App CRs can still be updated. The
+// returned warnings name entries referencing objects which do not exist.
+// Objects outside of the namespace of the App CR are only looked up when
+// referencesReviewed is set, i.e. the user is allowed to get them, as the
+// lookup uses the privileges of the admission controller.
+func (v *Validator) validateExtraConfigs(ctx context.Context, app v1alpha1.App, currentApp *v1alpha1.App, referencesReviewed bool) ([]string, error) {
+	var current []v1alpha1.AppExtraConfig
+	if currentApp != nil {
+		current = key.ExtraConfigs(*currentApp)
+	}
+
+	extraConfigs := key.ExtraConfigs(app)
+	path := field.NewPath("spec", "extraConfigs")
+
+	var errs field.ErrorList
+	var warnings []string
+	for i, c := range extraConfigs {
+		if slices.Contains(current, c) {
+			continue
+		}
+
+		p := path.Index(i)
+
+		kind := extraConfigKind(c)
+		if !slices.Contains(extraConfigKinds, kind) {
+			errs = append(errs, field.NotSupported(p.Child("kind"), c.Kind, extraConfigKinds))
+		}
+		if c.Name == "" {
+			errs = append(errs, field.Required(p.Child("name"), ""))
+		}
+		if c.Namespace == "" {
+			errs = append(errs, field.Required(p.Child("namespace"), ""))
+		}
+
+		managed := isManagedExtraConfig(app, c)
+		switch {
+		case c.Priority < bottomPriority || c.Priority > topPriority:
+			errs = append(errs, field.Invalid(p.Child("priority"), c.Priority, fmt.Sprintf("must be between %d and %d", bottomPriority, topPriority)))
+		case (c.Priority == bottomPriority || c.Priority == topPriority) && !managed:
+			errs = append(errs, field.Invalid(p.Child("priority"), c.Priority, "is reserved for entries managed by the admission controller"))
+		}
+
+		for j, other := range extraConfigs[:i] {
+			if extraConfigKind(other) == kind && other.Name == c.Name && other.Namespace == c.Namespace && other.Priority != c.Priority {
+				errs = append(errs, field.Duplicate(p, fmt.Sprintf("%s %#q in namespace %#q is listed in %s with priority %d already", kind, c.Name, c.Namespace, path.Index(j), other.Priority)))
+			}
+		}
+
+		// The objects of the mutator may not exist yet, e.g. when the
+		// cluster is created together with its apps.
+		if managed || c.Name == "" || c.Namespace == "" || !slices.Contains(extraConfigKinds, kind) {
+			continue
+		}
+		if c.Namespace != app.Namespace && !referencesReviewed {
+			continue
+		}
+
+		exists, err := v.extraConfigExists(ctx, kind, c.Name, c.Namespace)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !exists {
+			warnings = append(warnings, fmt.Sprintf("%s %#q in namespace %#q referenced in %s does not exist", kind, c.Name, c.Namespace, p))
+		}
+	}
+
+	if len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, e := range errs {
+			messages = append(messages, e.Error())
+		}
+		err := microerror.Maskf(invalidExtraConfigsError, "app %#q in namespace %#q has invalid extraConfigs: %s", app.Name, app.Namespace, strings.Join(messages, ", "))
+
+		return nil, validator.WithCauses(err, errs)
+	}
+
+	return warnings, nil
+}
+
+// extraConfigKind returns the kind of the entry, defaulting to configMap like
+// app-operator does.
+func extraConfigKind(c v1alpha1.AppExtraConfig) string {
+	if c.Kind == "" {
+		return extraConfigKindConfigMap
+	}
+
+	return c.Kind
+}
+
+func (v *Validator) extraConfigExists(ctx context.Context, kind, name, namespace string) (bool, error) {
+	var err error
+	switch kind {
+	case extraConfigKindSecret:
+		_, err = v.k8sClient.K8sClient().CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	default:
+		_, err = v.k8sClient.K8sClient().CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return true, nil
+}
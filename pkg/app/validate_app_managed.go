@@ -0,0 +1,118 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/app/v8/pkg/key"
+	"github.com/giantswarm/microerror"
+)
+
+// Classes of fields managed by the mutator, which can be protected from
+// being removed or changed on updates.
+const (
+	// ManagedClusterValues is the cluster values ConfigMap in
+	// .spec.extraConfigs.
+	ManagedClusterValues = "cluster-values"
+	// ManagedPSPRemoval are the psp-removal-patch ConfigMaps in
+	// .spec.extraConfigs and the PSP status label.
+	ManagedPSPRemoval = "psp-removal"
+	// ManagedKubeConfig is the kubeconfig Secret and context in
+	// .spec.kubeConfig.
+	ManagedKubeConfig = "kubeconfig"
+)
+
+// ManagedFields lists all classes of managed fields.
+var ManagedFields = []string{ManagedClusterValues, ManagedPSPRemoval, ManagedKubeConfig}
+
+// checkManagedFields returns a managedFieldChangedError when the update
+// removes or changes fields of the protected classes which the current App
+// CR has as set by the mutator. Entries of .spec.extraConfigs are managed
+// when they match the ones added by the mutator exactly, and entries with
+// the same name are considered changes of them.
+func checkManagedFields(protected []string, app, currentApp v1alpha1.App) error {
+	var changes []string
+
+	if slices.Contains(protected, ManagedClusterValues) {
+		changes = append(changes, extraConfigChanges(app, currentApp, func(c v1alpha1.AppExtraConfig) bool {
+			return isClusterValuesExtraConfig(currentApp, c)
+		})...)
+	}
+
+	if slices.Contains(protected, ManagedPSPRemoval) {
+		changes = append(changes, extraConfigChanges(app, currentApp, func(c v1alpha1.AppExtraConfig) bool {
+			return isPSPRemovalExtraConfig(currentApp, c)
+		})...)
+
+		if currentApp.Labels[pspLabelKey] == pspLabelVal && app.Labels[pspLabelKey] != pspLabelVal {
+			changes = append(changes, fmt.Sprintf("label %#q must not be changed from %#q", pspLabelKey, pspLabelVal))
+		}
+	}
+
+	// The kubeconfig is only managed for apps not installed in the
+	// management cluster, which is a privileged field of its own.
+	if slices.Contains(protected, ManagedKubeConfig) && !key.InCluster(app) && !key.InCluster(currentApp) {
+		fields := []struct {
+			path  string
+			value func(v1alpha1.App) string
+		}{
+			{path: ".spec.kubeConfig.secret.name", value: key.KubeConfigSecretName},
+			{path: ".spec.kubeConfig.secret.namespace", value: key.KubeConfigSecretNamespace},
+			{path: ".spec.kubeConfig.context.name", value: key.KubeConfigContextName},
+		}
+		for _, f := range fields {
+			if current := f.value(currentApp); current != "" && f.value(app) != current {
+				changes = append(changes, fmt.Sprintf("%s must not be changed from %#q", f.path, current))
+			}
+		}
+	}
+
+	if len(changes) > 0 {
+		return microerror.Maskf(managedFieldChangedError, "fields of app %#q in namespace %#q managed by the admission controller are protected: %s", app.Name, app.Namespace, strings.Join(changes, ", "))
+	}
+
+	return nil
+}
+
+// isManagedExtraConfig returns whether the entry of .spec.extraConfigs of
+// the App CR is one added by the mutator.
+func isManagedExtraConfig(app v1alpha1.App, c v1alpha1.AppExtraConfig) bool {
+	return isClusterValuesExtraConfig(app, c) || isPSPRemovalExtraConfig(app, c)
+}
+
+func isClusterValuesExtraConfig(app v1alpha1.App, c v1alpha1.AppExtraConfig) bool {
+	return c.Kind == extraConfigKindConfigMap && c.Name == key.ClusterConfigMapName(app) && c.Namespace == app.Namespace && c.Priority == bottomPriority
+}
+
+func isPSPRemovalExtraConfig(app v1alpha1.App, c v1alpha1.AppExtraConfig) bool {
+	return c.Kind == extraConfigKindConfigMap && strings.HasPrefix(c.Name, defaultExtraConfigName) && c.Namespace == app.Namespace && c.Priority == topPriority
+}
+
+// extraConfigChanges returns the changes of the managed entries of
+// .spec.extraConfigs of the current App CR.
+func extraConfigChanges(app, currentApp v1alpha1.App, managed func(v1alpha1.AppExtraConfig) bool) []string {
+	var changes []string
+	for _, m := range key.ExtraConfigs(currentApp) {
+		if !managed(m) {
+			continue
+		}
+
+		if !slices.Contains(key.ExtraConfigs(app), m) {
+			changes = append(changes, fmt.Sprintf(".spec.extraConfigs entry %s must not be removed", formatExtraConfig(m)))
+		}
+
+		for _, c := range key.ExtraConfigs(app) {
+			if c.Name == m.Name && c != m {
+				changes = append(changes, fmt.Sprintf(".spec.extraConfigs entry %s must not be changed to %s", formatExtraConfig(m), formatExtraConfig(c)))
+			}
+		}
+	}
+
+	return changes
+}
+
+func formatExtraConfig(c v1alpha1.AppExtraConfig) string {
+	return fmt.Sprintf("%s %#q in namespace %#q with priority %d", c.Kind, c.Name, c.Namespace, c.Priority)
+}
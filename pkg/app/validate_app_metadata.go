@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/app/v8/pkg/key"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	authv1 "k8s.io/api/authentication/v1"
+)
+
+const (
+	serviceAccountPrefix = "system:serviceaccount:"
+)
+
+// isServiceAccount returns whether the user is a service account, which
+// are the only whitelisted users allowed to change protected metadata.
+func isServiceAccount(userInfo authv1.UserInfo) bool {
+	return strings.HasPrefix(userInfo.Username, serviceAccountPrefix)
+}
+
+// checkProtectedMetadata returns a protectedMetadataChangedError when the
+// update adds or removes any of the protected finalizers, or sets, removes
+// or changes the value of any of the protected labels. Setting the version
+// label of App CRs without one or with the legacy one is done by the
+// mutator and so not considered a change.
+func checkProtectedMetadata(finalizers, labels []string, app, currentApp v1alpha1.App) error {
+	var changes []string
+
+	for _, f := range finalizers {
+		had := slices.Contains(currentApp.Finalizers, f)
+		has := slices.Contains(app.Finalizers, f)
+		switch {
+		case had && !has:
+			changes = append(changes, fmt.Sprintf("finalizer %#q must not be removed", f))
+		case !had && has:
+			changes = append(changes, fmt.Sprintf("finalizer %#q must not be added", f))
+		}
+	}
+
+	for _, l := range labels {
+		current, had := currentApp.Labels[l]
+		value, has := app.Labels[l]
+		if l == label.AppOperatorVersion && (current == "" || current == key.LegacyAppVersionLabel) {
+			continue
+		}
+
+		switch {
+		case had && !has:
+			changes = append(changes, fmt.Sprintf("label %#q must not be removed", l))
+		case !had && has:
+			changes = append(changes, fmt.Sprintf("label %#q must not be set", l))
+		case current != value:
+			changes = append(changes, fmt.Sprintf("label %#q must not be changed from %#q to %#q", l, current, value))
+		}
+	}
+
+	if len(changes) > 0 {
+		return microerror.Maskf(protectedMetadataChangedError, "metadata of app %#q in namespace %#q is protected and may only be changed by whitelisted service accounts: %s", app.Name, app.Namespace, strings.Join(changes, ", "))
+	}
+
+	return nil
+}
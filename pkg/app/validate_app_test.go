@@ -2,26 +2,37 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/k8smetadata/pkg/label"
 	"github.com/giantswarm/micrologger/microloggertest"
 	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	authv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/selection"
 	clientgofake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
 
+	"github.com/giantswarm/app-admission-controller/v2/internal/expression"
 	"github.com/giantswarm/app-admission-controller/v2/internal/recorder"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/access"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/deletion"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
 	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/validator"
 )
 
 // This client has been added as a way to work around the error coming from here:
@@ -1685,3 +1696,802 @@ func newTestCatalog(name, namespace string) *v1alpha1.Catalog {
 		},
 	}
 }
+
+func Test_checkManagedFields(t *testing.T) {
+	clusterValues := v1alpha1.AppExtraConfig{Kind: "configMap", Name: "abc01-cluster-values", Namespace: "abc01", Priority: bottomPriority}
+	pspRemoval := v1alpha1.AppExtraConfig{Kind: "configMap", Name: "psp-removal-patch", Namespace: "abc01", Priority: topPriority}
+	userConfig := v1alpha1.AppExtraConfig{Kind: "secret", Name: "user-values", Namespace: "abc01", Priority: 50}
+
+	newApp := func(extraConfigs ...v1alpha1.AppExtraConfig) v1alpha1.App {
+		return v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "abc01",
+				Labels: map[string]string{
+					pspLabelKey: pspLabelVal,
+				},
+			},
+			Spec: v1alpha1.AppSpec{
+				ExtraConfigs: extraConfigs,
+				KubeConfig: v1alpha1.AppSpecKubeConfig{
+					Context: v1alpha1.AppSpecKubeConfigContext{Name: "abc01"},
+					Secret:  v1alpha1.AppSpecKubeConfigSecret{Name: "abc01-kubeconfig", Namespace: "abc01"},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		protected    []string
+		app          v1alpha1.App
+		currentApp   v1alpha1.App
+		expectedErr  bool
+		expectedText string
+	}{
+		{
+			name:       "case 0: unchanged managed fields",
+			protected:  ManagedFields,
+			app:        newApp(clusterValues, userConfig, pspRemoval),
+			currentApp: newApp(clusterValues, pspRemoval),
+		},
+		{
+			name:         "case 1: lowered priority of the cluster values",
+			protected:    ManagedFields,
+			app:          newApp(v1alpha1.AppExtraConfig{Kind: "configMap", Name: "abc01-cluster-values", Namespace: "abc01", Priority: 0}),
+			currentApp:   newApp(clusterValues),
+			expectedErr:  true,
+			expectedText: "must not be changed to configMap `abc01-cluster-values` in namespace `abc01` with priority 0",
+		},
+		{
+			name:         "case 2: changed namespace of the cluster values next to the restored entry",
+			protected:    ManagedFields,
+			app:          newApp(v1alpha1.AppExtraConfig{Kind: "configMap", Name: "abc01-cluster-values", Namespace: "other", Priority: bottomPriority}, clusterValues),
+			currentApp:   newApp(clusterValues),
+			expectedErr:  true,
+			expectedText: "in namespace `other`",
+		},
+		{
+			name:         "case 3: removed PSP removal patch",
+			protected:    ManagedFields,
+			app:          newApp(clusterValues),
+			currentApp:   newApp(clusterValues, pspRemoval),
+			expectedErr:  true,
+			expectedText: "must not be removed",
+		},
+		{
+			name:       "case 4: removed PSP removal patch not protected",
+			protected:  []string{ManagedClusterValues},
+			app:        newApp(clusterValues),
+			currentApp: newApp(clusterValues, pspRemoval),
+		},
+		{
+			name:      "case 5: changed PSP label",
+			protected: []string{ManagedPSPRemoval},
+			app: func() v1alpha1.App {
+				app := newApp()
+				delete(app.Labels, pspLabelKey)
+				return app
+			}(),
+			currentApp:   newApp(),
+			expectedErr:  true,
+			expectedText: "label `policy.giantswarm.io/psp-status`",
+		},
+		{
+			name:      "case 6: changed kubeconfig secret",
+			protected: []string{ManagedKubeConfig},
+			app: func() v1alpha1.App {
+				app := newApp()
+				app.Spec.KubeConfig.Secret.Namespace = "other"
+				return app
+			}(),
+			currentApp:   newApp(),
+			expectedErr:  true,
+			expectedText: ".spec.kubeConfig.secret.namespace must not be changed from `abc01`",
+		},
+		{
+			name:       "case 7: user entries may be changed",
+			protected:  ManagedFields,
+			app:        newApp(clusterValues),
+			currentApp: newApp(clusterValues, userConfig),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkManagedFields(tc.protected, tc.app, tc.currentApp)
+			switch {
+			case err != nil && !tc.expectedErr:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.expectedErr:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !IsManagedFieldChanged(err):
+				t.Fatalf("error == %#v, want managed field changed", err)
+			case err != nil && !strings.Contains(err.Error(), tc.expectedText):
+				t.Fatalf("error == %q, want containing %q", err.Error(), tc.expectedText)
+			}
+		})
+	}
+}
+
+func Test_validateExtraConfigs(t *testing.T) {
+	clusterValues := v1alpha1.AppExtraConfig{Kind: "configMap", Name: "abc01-cluster-values", Namespace: "abc01", Priority: bottomPriority}
+	userConfig := v1alpha1.AppExtraConfig{Kind: "secret", Name: "user-values", Namespace: "abc01", Priority: 50}
+
+	newApp := func(extraConfigs ...v1alpha1.AppExtraConfig) v1alpha1.App {
+		return v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "abc01",
+				Labels: map[string]string{
+					"giantswarm.io/cluster": "abc01",
+				},
+			},
+			Spec: v1alpha1.AppSpec{
+				ExtraConfigs: extraConfigs,
+			},
+		}
+	}
+
+	tests := []struct {
+		name               string
+		app                v1alpha1.App
+		currentApp         *v1alpha1.App
+		referencesReviewed bool
+		expectedErr        bool
+		expectedCauses     []string
+		expectedWarnings   int
+	}{
+		{
+			name: "case 0: managed and existing entries",
+			app:  newApp(clusterValues, userConfig),
+		},
+		{
+			name:           "case 1: unknown kind and missing name",
+			app:            newApp(v1alpha1.AppExtraConfig{Kind: "configmap", Namespace: "abc01", Priority: 25}),
+			expectedErr:    true,
+			expectedCauses: []string{"spec.extraConfigs[0].kind", "spec.extraConfigs[0].name"},
+		},
+		{
+			name:           "case 2: priority out of range",
+			app:            newApp(v1alpha1.AppExtraConfig{Kind: "secret", Name: "user-values", Namespace: "abc01", Priority: 151}),
+			expectedErr:    true,
+			expectedCauses: []string{"spec.extraConfigs[0].priority"},
+		},
+		{
+			name:           "case 3: reserved priority",
+			app:            newApp(v1alpha1.AppExtraConfig{Kind: "secret", Name: "user-values", Namespace: "abc01", Priority: topPriority}),
+			expectedErr:    true,
+			expectedCauses: []string{"spec.extraConfigs[0].priority"},
+		},
+		{
+			name: "case 4: object listed twice with different priorities",
+			app: newApp(userConfig, v1alpha1.AppExtraConfig{
+				Kind: "secret", Name: "user-values", Namespace: "abc01", Priority: 60,
+			}),
+			expectedErr:    true,
+			expectedCauses: []string{"spec.extraConfigs[1]"},
+		},
+		{
+			name: "case 5: object listed twice with the defaulted kind",
+			app: newApp(
+				v1alpha1.AppExtraConfig{Name: "user-values", Namespace: "abc01", Priority: 25},
+				v1alpha1.AppExtraConfig{Kind: "configMap", Name: "user-values", Namespace: "abc01", Priority: 60},
+			),
+			expectedErr:    true,
+			expectedCauses: []string{"spec.extraConfigs[1]"},
+		},
+		{
+			name:             "case 6: missing object causes a warning",
+			app:              newApp(v1alpha1.AppExtraConfig{Kind: "configMap", Name: "missing", Namespace: "abc01", Priority: 25}),
+			expectedWarnings: 1,
+		},
+		{
+			name: "case 7: entries of the current app are not validated",
+			app: newApp(v1alpha1.AppExtraConfig{
+				Kind: "secret", Name: "user-values", Namespace: "abc01", Priority: topPriority,
+			}),
+			currentApp: func() *v1alpha1.App {
+				app := newApp(v1alpha1.AppExtraConfig{Kind: "secret", Name: "user-values", Namespace: "abc01", Priority: topPriority})
+				return &app
+			}(),
+		},
+		{
+			name: "case 8: missing object in another namespace is not looked up without review",
+			app:  newApp(v1alpha1.AppExtraConfig{Kind: "secret", Name: "missing", Namespace: "org-other", Priority: 25}),
+		},
+		{
+			name:               "case 9: missing object in another namespace causes a warning after review",
+			app:                newApp(v1alpha1.AppExtraConfig{Kind: "secret", Name: "missing", Namespace: "org-other", Priority: 25}),
+			referencesReviewed: true,
+			expectedWarnings:   1,
+		},
+	}
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		K8sClient: clientgofake.NewClientset(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "user-values", Namespace: "abc01"},
+			},
+		),
+	})
+
+	v := &Validator{
+		k8sClient: k8sClient,
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings, err := v.validateExtraConfigs(context.Background(), tc.app, tc.currentApp, tc.referencesReviewed)
+			switch {
+			case err != nil && !tc.expectedErr:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.expectedErr:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !IsInvalidExtraConfigs(err):
+				t.Fatalf("error == %#v, want invalid extra configs", err)
+			}
+
+			var causes []string
+			for _, c := range validator.Causes(err) {
+				causes = append(causes, c.Field)
+			}
+			if !reflect.DeepEqual(causes, tc.expectedCauses) {
+				t.Fatalf("causes == %v, want %v", causes, tc.expectedCauses)
+			}
+
+			if len(warnings) != tc.expectedWarnings {
+				t.Fatalf("warnings == %v, want %d", warnings, tc.expectedWarnings)
+			}
+		})
+	}
+}
+
+func Test_checkProtectedMetadata(t *testing.T) {
+	finalizers := []string{"operatorkit.giantswarm.io/app-operator-app"}
+	labels := []string{"app-operator.giantswarm.io/version", "giantswarm.io/cluster"}
+
+	newApp := func(version string, finalizers ...string) v1alpha1.App {
+		return v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "hello-world",
+				Namespace:  "abc01",
+				Finalizers: finalizers,
+				Labels: map[string]string{
+					"app-operator.giantswarm.io/version": version,
+					"giantswarm.io/cluster":              "abc01",
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		app          v1alpha1.App
+		currentApp   v1alpha1.App
+		expectedErr  bool
+		expectedText string
+	}{
+		{
+			name:       "case 0: unchanged metadata",
+			app:        newApp("3.0.0", finalizers...),
+			currentApp: newApp("3.0.0", finalizers...),
+		},
+		{
+			name:         "case 1: removed finalizer",
+			app:          newApp("3.0.0"),
+			currentApp:   newApp("3.0.0", finalizers...),
+			expectedErr:  true,
+			expectedText: "finalizer `operatorkit.giantswarm.io/app-operator-app` must not be removed",
+		},
+		{
+			name:         "case 2: changed version label",
+			app:          newApp("0.0.0", finalizers...),
+			currentApp:   newApp("3.0.0", finalizers...),
+			expectedErr:  true,
+			expectedText: "label `app-operator.giantswarm.io/version` must not be changed from `3.0.0` to `0.0.0`",
+		},
+		{
+			name: "case 3: removed cluster label",
+			app: func() v1alpha1.App {
+				app := newApp("3.0.0")
+				delete(app.Labels, "giantswarm.io/cluster")
+				return app
+			}(),
+			currentApp:   newApp("3.0.0"),
+			expectedErr:  true,
+			expectedText: "label `giantswarm.io/cluster` must not be removed",
+		},
+		{
+			name:       "case 4: legacy version label set by the mutator",
+			app:        newApp("3.0.0"),
+			currentApp: newApp("1.0.0"),
+		},
+		{
+			name:       "case 5: unprotected finalizers may be changed",
+			app:        newApp("3.0.0", "example.com/cleanup"),
+			currentApp: newApp("3.0.0"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkProtectedMetadata(finalizers, labels, tc.app, tc.currentApp)
+			switch {
+			case err != nil && !tc.expectedErr:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.expectedErr:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !IsProtectedMetadataChanged(err):
+				t.Fatalf("error == %#v, want protected metadata changed", err)
+			case err != nil && !strings.Contains(err.Error(), tc.expectedText):
+				t.Fatalf("error == %q, want containing %q", err.Error(), tc.expectedText)
+			}
+		})
+	}
+}
+
+func Test_ValidateDelete(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   string
+		expectedError bool
+	}{
+		{
+			name:          "case 0: unconfirmed deletion of critical app",
+			expectedError: true,
+		},
+		{
+			name:        "case 1: confirmed deletion of critical app",
+			annotations: `"annotations": {"app-admission-controller.giantswarm.io/confirm-deletion": "true"},`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = v1alpha1.AddToScheme(scheme)
+
+			k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+				CtrlClient: fake.NewClientBuilder().WithScheme(scheme).Build(),
+				K8sClient:  clientgofake.NewClientset(),
+			})
+
+			ins, err := secins.New(secins.Config{Logger: microloggertest.New()})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			guard, err := deletion.New(deletion.Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Inspector: ins,
+
+				Names: []string{"chart-operator"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			v, err := NewValidator(ValidatorConfig{
+				Event:     recorder.Discard{},
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Provider:  "aws",
+				Inspector: ins,
+
+				DeletionGuard: guard,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if ops := v.Webhook().Rules[0].Operations; ops[len(ops)-1] != admissionregistrationv1.Delete {
+				t.Fatalf("operations == %v, want DELETE", ops)
+			}
+
+			allowed, err := v.Validate(&admissionv1.AdmissionRequest{
+				Operation: admissionv1.Delete,
+				UserInfo:  authv1.UserInfo{Username: "jane"},
+				OldObject: runtime.RawExtension{
+					Raw: []byte(`{
+	"apiVersion": "application.giantswarm.io/v1alpha1",
+	"kind": "App",
+	"metadata": {
+		` + tc.annotations + `
+		"name": "chart-operator",
+		"namespace": "giantswarm"
+	},
+	"spec": {
+		"catalog": "control-plane-catalog",
+		"name": "chart-operator",
+		"namespace": "giantswarm",
+		"version": "1.0.0"
+	}
+}`),
+				},
+			})
+			switch {
+			case err != nil && !tc.expectedError:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.expectedError:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !deletion.IsCriticalAppDeletion(err):
+				t.Fatalf("error == %#v, want critical app deletion", err)
+			case err == nil && !allowed:
+				t.Fatalf("allowed == false, want true")
+			}
+		})
+	}
+}
+
+func Test_ValidateProtectedMetadataOnDeletion(t *testing.T) {
+	newApp := func(finalizers string) []byte {
+		return []byte(`{
+	"apiVersion": "application.giantswarm.io/v1alpha1",
+	"kind": "App",
+	"metadata": {
+		"deletionTimestamp": "2026-01-01T00:00:00Z",
+		"finalizers": [` + finalizers + `],
+		"labels": {"app-operator.giantswarm.io/version": "3.0.0"},
+		"name": "hello-world",
+		"namespace": "abc01"
+	},
+	"spec": {
+		"catalog": "giantswarm",
+		"name": "hello-world",
+		"namespace": "default",
+		"version": "1.0.0"
+	}
+}`)
+	}
+
+	tests := []struct {
+		name          string
+		userInfo      authv1.UserInfo
+		expectedError bool
+	}{
+		{
+			name:          "case 0: finalizer removed by non-whitelisted user",
+			userInfo:      authv1.UserInfo{Username: "jane"},
+			expectedError: true,
+		},
+		{
+			name:     "case 1: finalizer removed by whitelisted service account",
+			userInfo: authv1.UserInfo{Username: "system:serviceaccount:giantswarm:app-operator"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+				CtrlClient: fake.NewClientBuilder().Build(),
+				K8sClient:  clientgofake.NewClientset(),
+			})
+
+			ins, err := secins.New(secins.Config{
+				Logger:        microloggertest.New(),
+				UserWhitelist: []string{"system:serviceaccount:giantswarm:"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			v, err := NewValidator(ValidatorConfig{
+				Event:     recorder.Discard{},
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Provider:  "aws",
+				Inspector: ins,
+
+				ProtectedFinalizers: []string{"operatorkit.giantswarm.io/app-operator-app"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			allowed, err := v.Validate(&admissionv1.AdmissionRequest{
+				Operation: admissionv1.Update,
+				UserInfo:  tc.userInfo,
+				Object:    runtime.RawExtension{Raw: newApp(``)},
+				OldObject: runtime.RawExtension{Raw: newApp(`"operatorkit.giantswarm.io/app-operator-app"`)},
+			})
+			switch {
+			case err != nil && !tc.expectedError:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.expectedError:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !IsProtectedMetadataChanged(err):
+				t.Fatalf("error == %#v, want protected metadata changed", err)
+			case err == nil && !allowed:
+				t.Fatalf("allowed == false, want true")
+			}
+		})
+	}
+}
+
+func Test_ValidateAccessReviewError(t *testing.T) {
+	clientset := clientgofake.NewClientset()
+	clientset.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("apiserver unavailable")
+	})
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		CtrlClient: fake.NewClientBuilder().Build(),
+		K8sClient:  clientset,
+	})
+
+	ins, err := secins.New(secins.Config{Logger: microloggertest.New()})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	reviewer, err := access.New(access.Config{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	enforcer, err := enforcement.New(enforcement.Config{
+		Logger: microloggertest.New(),
+		Modes:  map[string]enforcement.Mode{enforcement.RuleReferenceAccess: enforcement.Warn},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	v, err := NewValidator(ValidatorConfig{
+		Event:     recorder.Discard{},
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Provider:  "aws",
+		Inspector: ins,
+
+		AccessReviewer: reviewer,
+		Enforcer:       enforcer,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// Failing to review the access must reject the app, even though
+	// violations of the rule only cause warnings.
+	_, err = v.Validate(&admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		UserInfo:  authv1.UserInfo{Username: "jane"},
+		Object: runtime.RawExtension{
+			Raw: []byte(`{
+	"apiVersion": "application.giantswarm.io/v1alpha1",
+	"kind": "App",
+	"metadata": {
+		"name": "hello-world",
+		"namespace": "org-acme"
+	},
+	"spec": {
+		"catalog": "giantswarm",
+		"name": "hello-world",
+		"namespace": "default",
+		"userConfig": {
+			"configMap": {
+				"name": "hello-world-user-values",
+				"namespace": "org-acme"
+			}
+		},
+		"version": "1.0.0"
+	}
+}`),
+		},
+	})
+	if err == nil {
+		t.Fatalf("error == nil, want non-nil")
+	}
+	if !strings.Contains(err.Error(), "apiserver unavailable") {
+		t.Fatalf("error == %q, want containing %q", err.Error(), "apiserver unavailable")
+	}
+	if rule := enforcement.Rule(err); rule != "" {
+		t.Fatalf("rule == %#q, want none", rule)
+	}
+}
+
+func Test_ValidateAccessReviewVersionLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+	}{
+		{
+			name:    "case 0: legacy version label",
+			version: "2.9.0",
+		},
+		{
+			name:    "case 1: non-semver version label",
+			version: "legacy",
+		},
+		{
+			name: "case 2: missing version label",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := clientgofake.NewClientset()
+			clientset.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				r := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				r.Status.Allowed = false
+				return true, r, nil
+			})
+
+			k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+				CtrlClient: fake.NewClientBuilder().Build(),
+				K8sClient:  clientset,
+			})
+
+			ins, err := secins.New(secins.Config{Logger: microloggertest.New()})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			reviewer, err := access.New(access.Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			v, err := NewValidator(ValidatorConfig{
+				Event:     recorder.Discard{},
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Provider:  "aws",
+				Inspector: ins,
+
+				AccessReviewer: reviewer,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			app := v1alpha1.App{
+				TypeMeta: v1alpha1.NewAppTypeMeta(),
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hello-world",
+					Namespace: "demo0",
+				},
+				Spec: v1alpha1.AppSpec{
+					Catalog:   "giantswarm",
+					Name:      "hello-world",
+					Namespace: "demo0",
+					UserConfig: v1alpha1.AppSpecUserConfig{
+						Secret: v1alpha1.AppSpecUserConfigSecret{
+							Name:      "other-credentials",
+							Namespace: "other",
+						},
+					},
+					Version: "1.0.0",
+				},
+			}
+			if tc.version != "" {
+				app.Labels = map[string]string{label.AppOperatorVersion: tc.version}
+			}
+
+			raw, err := json.Marshal(app)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			// The version label must not skip the access review, as
+			// app-operator reads the references of these apps too.
+			allowed, err := v.Validate(&admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				UserInfo:  authv1.UserInfo{Username: "jane"},
+				Object:    runtime.RawExtension{Raw: raw},
+			})
+			if !access.IsAccessDenied(err) {
+				t.Fatalf("error == %#v, want access denied", err)
+			}
+			if allowed {
+				t.Fatalf("allowed == true, want false")
+			}
+		})
+	}
+}
+
+func Test_ValidateRulesVersionLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+	}{
+		{
+			name:    "case 0: legacy version label",
+			version: "2.9.0",
+		},
+		{
+			name:    "case 1: non-semver version label",
+			version: "legacy",
+		},
+		{
+			name: "case 2: missing version label",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+				CtrlClient: fake.NewClientBuilder().Build(),
+				K8sClient:  clientgofake.NewClientset(),
+			})
+
+			ins, err := secins.New(secins.Config{Logger: microloggertest.New()})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			evaluator, err := expression.New(expression.Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+
+				Rules: []expression.Rule{
+					{
+						Name:       "install-timeout",
+						Expression: "has(object.spec.install) && has(object.spec.install.timeout)",
+						Message:    "apps must set .spec.install.timeout",
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			v, err := NewValidator(ValidatorConfig{
+				Event:     recorder.Discard{},
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Provider:  "aws",
+				Inspector: ins,
+
+				RuleEvaluator: evaluator,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			app := v1alpha1.App{
+				TypeMeta: v1alpha1.NewAppTypeMeta(),
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hello-world",
+					Namespace: "demo0",
+				},
+				Spec: v1alpha1.AppSpec{
+					Catalog:   "giantswarm",
+					Name:      "hello-world",
+					Namespace: "demo0",
+					Version:   "1.0.0",
+				},
+			}
+			if tc.version != "" {
+				app.Labels = map[string]string{label.AppOperatorVersion: tc.version}
+			}
+
+			raw, err := json.Marshal(app)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			// The version label must not skip the rules, as they could be
+			// evaded by changing it otherwise.
+			allowed, err := v.Validate(&admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				UserInfo:  authv1.UserInfo{Username: "jane"},
+				Object:    runtime.RawExtension{Raw: raw},
+			})
+			if !expression.IsRuleViolation(err) {
+				t.Fatalf("error == %#v, want rule violation", err)
+			}
+			if allowed {
+				t.Fatalf("allowed == true, want false")
+			}
+		})
+	}
+}
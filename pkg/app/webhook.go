@@ -0,0 +1,55 @@
+package app
+
+import (
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+
+	"github.com/giantswarm/app-admission-controller/v2/pkg/webhook"
+)
+
+// appRules select the App CR requests sent to the webhooks.
+var appRules = []admissionregistrationv1.RuleWithOperations{
+	{
+		Operations: []admissionregistrationv1.OperationType{
+			admissionregistrationv1.Create,
+			admissionregistrationv1.Update,
+		},
+		Rule: admissionregistrationv1.Rule{
+			APIGroups:   []string{"application.giantswarm.io"},
+			APIVersions: []string{"v1alpha1"},
+			Resources:   []string{"apps"},
+		},
+	},
+}
+
+// Webhook returns the registration of the App CR mutating webhook.
+func (m *Mutator) Webhook() webhook.Registration {
+	return webhook.Registration{
+		Name:  "apps",
+		Path:  "/mutate/app",
+		Rules: appRules,
+
+		FailurePolicy:  admissionregistrationv1.Fail,
+		SideEffects:    admissionregistrationv1.SideEffectClassNoneOnDryRun,
+		TimeoutSeconds: 10,
+	}
+}
+
+// Webhook returns the registration of the App CR validating webhook. It
+// also selects DELETE requests when critical App CRs are guarded.
+func (v *Validator) Webhook() webhook.Registration {
+	rules := appRules
+	if v.deletionGuard != nil {
+		rules = []admissionregistrationv1.RuleWithOperations{*appRules[0].DeepCopy()}
+		rules[0].Operations = append(rules[0].Operations, admissionregistrationv1.Delete)
+	}
+
+	return webhook.Registration{
+		Name:  "apps",
+		Path:  "/validate/app",
+		Rules: rules,
+
+		FailurePolicy:  admissionregistrationv1.Fail,
+		SideEffects:    admissionregistrationv1.SideEffectClassNoneOnDryRun,
+		TimeoutSeconds: 10,
+	}
+}
@@ -0,0 +1,301 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/recorder"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/app"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/metrics"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/mutator"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+const (
+	// CheckMutation marks apps missing defaults the mutating webhook would
+	// add if the app was submitted now.
+	CheckMutation = "mutation"
+	// CheckSecurity marks apps rejected by the security inspector.
+	CheckSecurity = "security"
+	// CheckValidation marks apps rejected by the validating webhook for
+	// other reasons.
+	CheckValidation = "validation"
+	// CheckWarning marks apps admitted with warnings, e.g. violating rules
+	// in the warn mode.
+	CheckWarning = "warning"
+
+	eventReasonDrift     = "AuditDrift"
+	eventReasonViolation = "AuditViolation"
+)
+
+type Config struct {
+	Event     recorder.Interface
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+	// Mutator is expected to be configured in the dry run mode, so that
+	// audit runs do not create any objects.
+	Mutator *app.Mutator
+	// Validator is expected to be dedicated to audit runs, with a silent
+	// enforcer and without emitting events, so that audit runs are not
+	// mistaken for admissions.
+	Validator *app.Validator
+
+	Interval                 time.Duration
+	StatusConfigMapName      string
+	StatusConfigMapNamespace string
+}
+
+// Violation describes a single finding for an existing App CR.
+type Violation struct {
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+	Check     string `yaml:"check"`
+	Message   string `yaml:"message"`
+}
+
+// Report is the result of a single audit run.
+type Report struct {
+	Apps       int
+	Timestamp  time.Time
+	Violations []Violation
+}
+
+// Auditor periodically evaluates existing App CRs against the current
+// admission rules. The webhooks only see new writes, so without the audit
+// apps created before a rule changed are never evaluated again.
+type Auditor struct {
+	event     recorder.Interface
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+	mutator   *app.Mutator
+	validator *app.Validator
+
+	interval                 time.Duration
+	statusConfigMapName      string
+	statusConfigMapNamespace string
+}
+
+func New(config Config) (*Auditor, error) {
+	if config.Event == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Event must not be empty", config)
+	}
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Mutator == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Mutator must not be empty", config)
+	}
+	if config.Validator == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Validator must not be empty", config)
+	}
+
+	if config.Interval <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Interval must be greater than zero", config)
+	}
+	if config.StatusConfigMapName != "" && config.StatusConfigMapNamespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.StatusConfigMapNamespace must not be empty when %T.StatusConfigMapName is set", config, config)
+	}
+
+	a := &Auditor{
+		event:     config.Event,
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+		mutator:   config.Mutator,
+		validator: config.Validator,
+
+		interval:                 config.Interval,
+		statusConfigMapName:      config.StatusConfigMapName,
+		statusConfigMapNamespace: config.StatusConfigMapNamespace,
+	}
+
+	return a, nil
+}
+
+// Run audits existing apps every configured interval until the context is
+// cancelled.
+func (a *Auditor) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := a.Audit(ctx)
+		if err != nil {
+			a.logger.Errorf(ctx, err, "audit run failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Audit runs a single audit of all existing apps and reports the results as
+// metrics, events and, when configured, the status ConfigMap.
+func (a *Auditor) Audit(ctx context.Context) (Report, error) {
+	start := time.Now()
+
+	a.logger.Debugf(ctx, "auditing existing apps")
+
+	var apps v1alpha1.AppList
+	err := a.k8sClient.CtrlClient().List(ctx, &apps)
+	if err != nil {
+		return Report{}, microerror.Mask(err)
+	}
+
+	report := Report{
+		Timestamp: start,
+	}
+
+	for _, cr := range apps.Items {
+		if !cr.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		report.Apps++
+
+		violations := a.auditApp(ctx, cr)
+		report.Violations = append(report.Violations, violations...)
+	}
+
+	sort.Slice(report.Violations, func(i, j int) bool {
+		l, r := report.Violations[i], report.Violations[j]
+		if l.Namespace != r.Namespace {
+			return l.Namespace < r.Namespace
+		}
+		if l.Name != r.Name {
+			return l.Name < r.Name
+		}
+		return l.Check < r.Check
+	})
+
+	updateMetrics(report, time.Since(start))
+
+	err = a.writeStatus(ctx, report)
+	if err != nil {
+		return Report{}, microerror.Mask(err)
+	}
+
+	a.logger.Debugf(ctx, "audited %d apps, found %d violations", report.Apps, len(report.Violations))
+
+	return report, nil
+}
+
+func (a *Auditor) auditApp(ctx context.Context, cr v1alpha1.App) []Violation {
+	var violations []Violation
+
+	cr.TypeMeta = v1alpha1.NewAppTypeMeta()
+
+	raw, err := json.Marshal(cr)
+	if err != nil {
+		a.logger.Errorf(ctx, err, "failed to serialize app %#q in namespace %#q", cr.Name, cr.Namespace)
+		return nil
+	}
+
+	message, check, err := a.validate(raw)
+	if err != nil {
+		a.logger.Errorf(ctx, err, "failed to audit app %#q in namespace %#q", cr.Name, cr.Namespace)
+	} else if message != "" {
+		a.event.EmitWarning(ctx, &cr, eventReasonViolation, "app violates %s rules: %s", check, message)
+		violations = append(violations, Violation{
+			Namespace: cr.Namespace,
+			Name:      cr.Name,
+			Check:     check,
+			Message:   message,
+		})
+	}
+
+	patches, err := a.drift(ctx, cr, raw)
+	if err != nil {
+		a.logger.Errorf(ctx, err, "failed to compute drift of app %#q in namespace %#q", cr.Name, cr.Namespace)
+	} else if len(patches) > 0 {
+		var paths []string
+		for _, p := range patches {
+			paths = append(paths, p.Path)
+		}
+		message := fmt.Sprintf("missing defaults for %s", strings.Join(paths, ", "))
+
+		a.event.EmitWarning(ctx, &cr, eventReasonDrift, "%s", message)
+		violations = append(violations, Violation{
+			Namespace: cr.Namespace,
+			Name:      cr.Name,
+			Check:     CheckMutation,
+			Message:   message,
+		})
+	}
+
+	return violations
+}
+
+// validate runs the app through the validating webhook logic as if it was
+// being created. It returns the rejection or warning message along with the
+// check that produced it, or an empty message when the app is admitted
+// without warnings.
+func (a *Auditor) validate(raw []byte) (string, string, error) {
+	dryRun := true
+	request := &admissionv1.AdmissionRequest{
+		DryRun:    &dryRun,
+		Operation: admissionv1.Create,
+		Object: runtime.RawExtension{
+			Raw: raw,
+		},
+	}
+
+	allowed, warnings, _, err := a.validator.ValidateWithAudit(request)
+	if app.IsParsingFailed(err) {
+		return "", "", microerror.Mask(err)
+	} else if secins.IsSecurityViolationError(err) {
+		return err.Error(), CheckSecurity, nil
+	} else if err != nil {
+		return err.Error(), CheckValidation, nil
+	} else if !allowed {
+		return "app is not allowed", CheckValidation, nil
+	} else if len(warnings) > 0 {
+		return strings.Join(warnings, ", "), CheckWarning, nil
+	}
+
+	return "", "", nil
+}
+
+// drift returns patches the mutating webhook would apply to the app if it
+// was submitted now, skipping the ones that do not change it.
+func (a *Auditor) drift(ctx context.Context, cr v1alpha1.App, raw []byte) ([]mutator.PatchOperation, error) {
+	patches, err := a.mutator.MutateApp(ctx, cr, cr, admissionv1.Update)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	patches, err = mutator.Effective(raw, patches)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return patches, nil
+}
+
+func updateMetrics(report Report, duration time.Duration) {
+	metrics.AuditViolations.Reset()
+	for _, v := range report.Violations {
+		metrics.AuditViolations.WithLabelValues(v.Check, v.Namespace).Inc()
+	}
+
+	metrics.AuditApps.Set(float64(report.Apps))
+	metrics.AuditDuration.Set(duration.Seconds())
+	metrics.AuditLastRun.Set(float64(report.Timestamp.Add(duration).Unix()))
+}
@@ -0,0 +1,340 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/recorder"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/app"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/metrics"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+func Test_Auditor_Audit(t *testing.T) {
+	ctx := context.Background()
+
+	apps := []runtime.Object{
+		&v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "demo0",
+				Labels: map[string]string{
+					"app-operator.giantswarm.io/version": "0.0.0",
+					"app.kubernetes.io/name":             "hello-world",
+				},
+				Annotations: map[string]string{
+					"foo": "bar",
+				},
+			},
+			Spec: v1alpha1.AppSpec{
+				Catalog:   "giantswarm",
+				Name:      "hello-world",
+				Namespace: "demo0",
+				KubeConfig: v1alpha1.AppSpecKubeConfig{
+					InCluster: true,
+				},
+				UserConfig: v1alpha1.AppSpecUserConfig{
+					Secret: v1alpha1.AppSpecUserConfigSecret{
+						Name:      "vault-token",
+						Namespace: "giantswarm",
+					},
+				},
+				Version: "0.3.0",
+			},
+		},
+		&v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kiam",
+				Namespace: "eggs2",
+				Labels: map[string]string{
+					"app-operator.giantswarm.io/version": "5.5.0",
+					"app.kubernetes.io/name":             "kiam",
+				},
+				Annotations: map[string]string{
+					"foo": "bar",
+				},
+			},
+			Spec: v1alpha1.AppSpec{
+				Catalog:   "giantswarm",
+				Name:      "kiam",
+				Namespace: "kube-system",
+				KubeConfig: v1alpha1.AppSpecKubeConfig{
+					Context: v1alpha1.AppSpecKubeConfigContext{
+						Name: "eggs2-kubeconfig",
+					},
+					Secret: v1alpha1.AppSpecKubeConfigSecret{
+						Name:      "eggs2-kubeconfig",
+						Namespace: "eggs2",
+					},
+				},
+				Version: "1.4.0",
+			},
+		},
+		&v1alpha1.Catalog{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "giantswarm",
+				Namespace: "default",
+			},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+
+	fakeCtrlClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(apps...).
+		WithIndex(&v1alpha1.App{}, "metadata.name", func(obj client.Object) []string {
+			return []string{obj.GetName()}
+		}).
+		Build()
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		CtrlClient: fakeCtrlClient,
+		K8sClient: clientgofake.NewClientset(
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "eggs2-kubeconfig", Namespace: "eggs2"}},
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "vault-token", Namespace: "giantswarm"}},
+		),
+	})
+
+	event := recorder.New(recorder.Config{
+		K8sClient: k8sClient,
+		Component: "app-admission-controller",
+	})
+
+	ins, err := secins.New(secins.Config{
+		Logger:             microloggertest.New(),
+		NamespaceBlacklist: []string{"giantswarm"},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	v, err := app.NewValidator(app.ValidatorConfig{
+		Event:     event,
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Provider:  "aws",
+		Inspector: ins,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	m, err := app.NewMutator(app.MutatorConfig{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Provider:  "aws",
+		DryRun:    true,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	a, err := New(Config{
+		Event:     event,
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Mutator:   m,
+		Validator: v,
+
+		Interval:                 time.Minute,
+		StatusConfigMapName:      "app-admission-controller-audit",
+		StatusConfigMapNamespace: "giantswarm",
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	report, err := a.Audit(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	expectedViolations := []Violation{
+		{
+			Namespace: "demo0",
+			Name:      "hello-world",
+			Check:     CheckSecurity,
+			Message:   "security violation error: references to `giantswarm` namespace not allowed",
+		},
+		{
+			Namespace: "eggs2",
+			Name:      "kiam",
+			Check:     CheckMutation,
+			Message:   "missing defaults for /spec/extraConfigs/-",
+		},
+	}
+
+	if report.Apps != 2 {
+		t.Fatalf("report.Apps == %d, want 2", report.Apps)
+	}
+	if !reflect.DeepEqual(report.Violations, expectedViolations) {
+		t.Fatalf("want matching violations \n %s", cmp.Diff(report.Violations, expectedViolations))
+	}
+
+	status, err := k8sClient.K8sClient().CoreV1().ConfigMaps("giantswarm").Get(ctx, "app-admission-controller-audit", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if status.Data[statusKeyApps] != fmt.Sprint(report.Apps) {
+		t.Fatalf("status apps == %#q, want %#q", status.Data[statusKeyApps], fmt.Sprint(report.Apps))
+	}
+
+	// The dry run mode must not create any ConfigMaps apart from the
+	// status one.
+	cms, err := k8sClient.K8sClient().CoreV1().ConfigMaps("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if len(cms.Items) != 1 {
+		t.Fatalf("got %d ConfigMaps, want 1", len(cms.Items))
+	}
+}
+
+func Test_Auditor_Audit_Warnings(t *testing.T) {
+	ctx := context.Background()
+
+	apps := []runtime.Object{
+		&v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "demo0",
+				Labels: map[string]string{
+					"app-operator.giantswarm.io/version": "0.0.0",
+					"app.kubernetes.io/name":             "hello-world",
+				},
+			},
+			Spec: v1alpha1.AppSpec{
+				Catalog:   "giantswarm",
+				Name:      "hello-world",
+				Namespace: "demo0",
+				KubeConfig: v1alpha1.AppSpecKubeConfig{
+					InCluster: true,
+				},
+				UserConfig: v1alpha1.AppSpecUserConfig{
+					Secret: v1alpha1.AppSpecUserConfigSecret{
+						Name:      "vault-token",
+						Namespace: "giantswarm",
+					},
+				},
+				Version: "0.3.0",
+			},
+		},
+		&v1alpha1.Catalog{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "giantswarm",
+				Namespace: "default",
+			},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		CtrlClient: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithRuntimeObjects(apps...).
+			WithIndex(&v1alpha1.App{}, "metadata.name", func(obj client.Object) []string {
+				return []string{obj.GetName()}
+			}).
+			Build(),
+		K8sClient: clientgofake.NewClientset(
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "vault-token", Namespace: "giantswarm"}},
+		),
+	})
+
+	ins, err := secins.New(secins.Config{
+		Logger:             microloggertest.New(),
+		NamespaceBlacklist: []string{"giantswarm"},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	enforcer, err := enforcement.New(enforcement.Config{
+		Logger: microloggertest.New(),
+		Modes:  map[string]enforcement.Mode{enforcement.RuleBlacklistedReference: enforcement.Warn},
+		Silent: true,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	v, err := app.NewValidator(app.ValidatorConfig{
+		Event:     recorder.Discard{},
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Provider:  "aws",
+		Inspector: ins,
+		Enforcer:  enforcer,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	m, err := app.NewMutator(app.MutatorConfig{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Provider:  "aws",
+		DryRun:    true,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	a, err := New(Config{
+		Event:     recorder.Discard{},
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Mutator:   m,
+		Validator: v,
+
+		Interval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	decisions := testutil.ToFloat64(metrics.RuleDecisions.WithLabelValues(enforcement.RuleBlacklistedReference, string(enforcement.Warn)))
+
+	report, err := a.Audit(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	expectedViolations := []Violation{
+		{
+			Namespace: "demo0",
+			Name:      "hello-world",
+			Check:     CheckWarning,
+			Message:   "security violation error: references to `giantswarm` namespace not allowed",
+		},
+	}
+	if !reflect.DeepEqual(report.Violations, expectedViolations) {
+		t.Fatalf("want matching violations \n %s", cmp.Diff(report.Violations, expectedViolations))
+	}
+
+	// Audit runs must not be recorded as admission decisions.
+	if d := testutil.ToFloat64(metrics.RuleDecisions.WithLabelValues(enforcement.RuleBlacklistedReference, string(enforcement.Warn))); d != decisions {
+		t.Fatalf("rule decisions == %v, want %v", d, decisions)
+	}
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/app-admission-controller/v2/pkg/project"
+)
+
+const (
+	statusKeyApps       = "apps"
+	statusKeyLastRun    = "lastRun"
+	statusKeyViolations = "violations"
+)
+
+// writeStatus stores the audit report in the status ConfigMap, if one is
+// configured.
+func (a *Auditor) writeStatus(ctx context.Context, report Report) error {
+	if a.statusConfigMapName == "" {
+		return nil
+	}
+
+	violations := report.Violations
+	if violations == nil {
+		violations = []Violation{}
+	}
+
+	data, err := yaml.Marshal(violations)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      a.statusConfigMapName,
+			Namespace: a.statusConfigMapNamespace,
+			Labels: map[string]string{
+				label.ManagedBy: project.Name(),
+			},
+		},
+		Data: map[string]string{
+			statusKeyApps:       strconv.Itoa(report.Apps),
+			statusKeyLastRun:    report.Timestamp.UTC().Format(time.RFC3339),
+			statusKeyViolations: string(data),
+		},
+	}
+
+	client := a.k8sClient.K8sClient().CoreV1().ConfigMaps(a.statusConfigMapNamespace)
+
+	_, err = client.Update(ctx, cm, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
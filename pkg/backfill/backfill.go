@@ -0,0 +1,288 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"golang.org/x/time/rate"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/app-admission-controller/v2/pkg/app"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/mutator"
+)
+
+const (
+	// maxPatchAttempts bounds the attempts to patch an app changing
+	// concurrently.
+	maxPatchAttempts = 3
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+	// Mutator is expected to be configured in the dry run mode unless Apply
+	// is set, so that reporting does not create any objects.
+	Mutator *app.Mutator
+	Output  io.Writer
+
+	// Apply makes the backfiller patch the apps. By default the patches are
+	// only reported.
+	Apply     bool
+	Namespace string
+	// Rate is the maximum number of apps patched per second.
+	Rate     float64
+	Selector string
+}
+
+// Result describes patches computed for a single app.
+type Result struct {
+	Namespace string
+	Name      string
+	Patches   []mutator.PatchOperation
+	Applied   bool
+}
+
+// Backfiller applies the current mutation defaults to existing apps. When a
+// new default is introduced, apps created before are never mutated again
+// unless they are updated.
+type Backfiller struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+	mutator   *app.Mutator
+	output    io.Writer
+
+	apply     bool
+	limiter   *rate.Limiter
+	namespace string
+	selector  labels.Selector
+}
+
+func New(config Config) (*Backfiller, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Mutator == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Mutator must not be empty", config)
+	}
+	if config.Output == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Output must not be empty", config)
+	}
+
+	if config.Rate <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Rate must be greater than zero", config)
+	}
+
+	selector, err := labels.Parse(config.Selector)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Selector is invalid: %s", config, err)
+	}
+
+	b := &Backfiller{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+		mutator:   config.Mutator,
+		output:    config.Output,
+
+		apply:     config.Apply,
+		limiter:   rate.NewLimiter(rate.Limit(config.Rate), 1),
+		namespace: config.Namespace,
+		selector:  selector,
+	}
+
+	return b, nil
+}
+
+// Run computes the patches the mutating webhook would produce now for every
+// app matching the configuration, prints them and applies them if
+// configured to. Apps failing to be backfilled are logged and skipped, and
+// reported with a failedError once all apps are processed.
+func (b *Backfiller) Run(ctx context.Context) ([]Result, error) {
+	var apps v1alpha1.AppList
+	err := b.k8sClient.CtrlClient().List(ctx, &apps, &client.ListOptions{
+		Namespace:     b.namespace,
+		LabelSelector: b.selector,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var results []Result
+	var inspected, failed int
+	for _, cr := range apps.Items {
+		if !cr.DeletionTimestamp.IsZero() {
+			continue
+		}
+		inspected++
+
+		result, err := b.backfillApp(ctx, cr)
+		if err != nil {
+			b.logger.Errorf(ctx, err, "failed to backfill app %#q in namespace %#q", cr.Name, cr.Namespace)
+			failed++
+			continue
+		}
+		if len(result.Patches) == 0 {
+			continue
+		}
+
+		results = append(results, result)
+
+		err = b.print(result)
+		if err != nil {
+			return results, microerror.Mask(err)
+		}
+	}
+
+	action := "would be patched"
+	if b.apply {
+		action = "patched"
+	}
+
+	summary := fmt.Sprintf("%d apps inspected, %d apps %s", inspected, len(results), action)
+	if failed > 0 {
+		summary += fmt.Sprintf(", %d apps failed", failed)
+	}
+
+	_, err = fmt.Fprintln(b.output, summary)
+	if err != nil {
+		return results, microerror.Mask(err)
+	}
+
+	if failed > 0 {
+		return results, microerror.Maskf(failedError, "%d of %d apps failed", failed, inspected)
+	}
+
+	return results, nil
+}
+
+func (b *Backfiller) backfillApp(ctx context.Context, cr v1alpha1.App) (Result, error) {
+	result := Result{
+		Namespace: cr.Namespace,
+		Name:      cr.Name,
+	}
+
+	var err error
+	_, result.Patches, err = b.mutate(ctx, cr)
+	if err != nil {
+		return Result{}, microerror.Mask(err)
+	}
+
+	if !b.apply || len(result.Patches) == 0 {
+		return result, nil
+	}
+
+	err = b.limiter.Wait(ctx)
+	if err != nil {
+		return Result{}, microerror.Mask(err)
+	}
+
+	// The app may have changed since it was listed, and while waiting for
+	// the limiter, so the patches are computed again from the current app.
+	// They are only applied as long as the app is unchanged, as the
+	// preparatory ones, e.g. adding an empty `.spec.extraConfigs` list,
+	// overwrite whole fields.
+	var patchErr error
+	for attempt := 0; attempt < maxPatchAttempts; attempt++ {
+		resourceVersion := cr.ResourceVersion
+
+		err = b.k8sClient.CtrlClient().Get(ctx, client.ObjectKeyFromObject(&cr), &cr)
+		if apierrors.IsNotFound(err) {
+			b.logger.Debugf(ctx, "skipping app %#q in namespace %#q deleted in the meantime", cr.Name, cr.Namespace)
+			return Result{}, nil
+		} else if err != nil {
+			return Result{}, microerror.Mask(err)
+		}
+
+		// The patch failed although the app did not change, so it would
+		// fail again.
+		if patchErr != nil && cr.ResourceVersion == resourceVersion {
+			return Result{}, microerror.Mask(patchErr)
+		}
+
+		var patches []mutator.PatchOperation
+		patches, result.Patches, err = b.mutate(ctx, cr)
+		if err != nil {
+			return Result{}, microerror.Mask(err)
+		}
+		if len(result.Patches) == 0 {
+			return Result{}, nil
+		}
+
+		// All the patches are applied, as the effective ones may rely on
+		// the preparatory ones.
+		patches = append([]mutator.PatchOperation{mutator.PatchTest("/metadata/resourceVersion", cr.ResourceVersion)}, patches...)
+
+		data, err := json.Marshal(patches)
+		if err != nil {
+			return Result{}, microerror.Mask(err)
+		}
+
+		b.logger.Debugf(ctx, "patching app %#q in namespace %#q", cr.Name, cr.Namespace)
+
+		patchErr = b.k8sClient.CtrlClient().Patch(ctx, &cr, client.RawPatch(types.JSONPatchType, data))
+		if patchErr == nil {
+			result.Applied = true
+			return result, nil
+		}
+
+		b.logger.Debugf(ctx, "failed to patch app %#q in namespace %#q: %s", cr.Name, cr.Namespace, patchErr)
+	}
+
+	return Result{}, microerror.Maskf(conflictError, "app %#q in namespace %#q changed while patching it %d times: %s", cr.Name, cr.Namespace, maxPatchAttempts, patchErr)
+}
+
+// mutate returns all the patches the mutating webhook would produce now for
+// the app, and the effective ones.
+func (b *Backfiller) mutate(ctx context.Context, cr v1alpha1.App) ([]mutator.PatchOperation, []mutator.PatchOperation, error) {
+	cr.TypeMeta = v1alpha1.NewAppTypeMeta()
+
+	raw, err := json.Marshal(cr)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	patches, err := b.mutator.MutateApp(ctx, cr, cr, admissionv1.Update)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	effective, err := mutator.Effective(raw, patches)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	return patches, effective, nil
+}
+
+func (b *Backfiller) print(result Result) error {
+	_, err := fmt.Fprintf(b.output, "%s/%s:\n", result.Namespace, result.Name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, p := range result.Patches {
+		value, err := json.Marshal(p.Value)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		_, err = fmt.Fprintf(b.output, "  %s %s %s\n", p.Operation, p.Path, value)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,317 @@
+package backfill
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/giantswarm/app-admission-controller/v2/pkg/app"
+)
+
+func Test_Backfiller_Run(t *testing.T) {
+	tests := []struct {
+		name                 string
+		apply                bool
+		selector             string
+		expectedResults      int
+		expectedExtraConfigs []v1alpha1.AppExtraConfig
+	}{
+		{
+			name:            "case 0: report patches only",
+			expectedResults: 2,
+		},
+		{
+			name:            "case 1: report patches for selected apps",
+			selector:        "app.kubernetes.io/name=kiam",
+			expectedResults: 1,
+		},
+		{
+			name:            "case 2: apply patches to selected apps",
+			apply:           true,
+			selector:        "app.kubernetes.io/name=kiam",
+			expectedResults: 1,
+			expectedExtraConfigs: []v1alpha1.AppExtraConfig{
+				{
+					Kind:      "configMap",
+					Name:      "eggs2-cluster-values",
+					Namespace: "eggs2",
+					Priority:  1,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			scheme := runtime.NewScheme()
+			_ = v1alpha1.AddToScheme(scheme)
+
+			fakeCtrlClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithRuntimeObjects(
+					newTestApp("kiam", "eggs2"),
+					newTestApp("external-dns", "eggs2"),
+				).
+				Build()
+
+			k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+				CtrlClient: fakeCtrlClient,
+				K8sClient:  clientgofake.NewClientset(),
+			})
+
+			m, err := app.NewMutator(app.MutatorConfig{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Provider:  "aws",
+				DryRun:    !tc.apply,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			b, err := New(Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Mutator:   m,
+				Output:    &bytes.Buffer{},
+
+				Apply:    tc.apply,
+				Rate:     100,
+				Selector: tc.selector,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			results, err := b.Run(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if len(results) != tc.expectedResults {
+				t.Fatalf("got %d results, want %d", len(results), tc.expectedResults)
+			}
+			for _, r := range results {
+				if r.Applied != tc.apply {
+					t.Fatalf("result.Applied == %t, want %t", r.Applied, tc.apply)
+				}
+			}
+
+			var kiam v1alpha1.App
+			err = fakeCtrlClient.Get(ctx, client.ObjectKey{Name: "kiam", Namespace: "eggs2"}, &kiam)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if !reflect.DeepEqual(kiam.Spec.ExtraConfigs, tc.expectedExtraConfigs) {
+				t.Fatalf("want matching extra configs \n %s", cmp.Diff(kiam.Spec.ExtraConfigs, tc.expectedExtraConfigs))
+			}
+		})
+	}
+}
+
+func newTestApp(name, namespace string) *v1alpha1.App {
+	return &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app-operator.giantswarm.io/version": "5.5.0",
+				"app.kubernetes.io/name":             name,
+			},
+			Annotations: map[string]string{
+				"foo": "bar",
+			},
+		},
+		Spec: v1alpha1.AppSpec{
+			Catalog:   "giantswarm",
+			Name:      name,
+			Namespace: "kube-system",
+			KubeConfig: v1alpha1.AppSpecKubeConfig{
+				Context: v1alpha1.AppSpecKubeConfigContext{
+					Name: namespace + "-kubeconfig",
+				},
+				Secret: v1alpha1.AppSpecKubeConfigSecret{
+					Name:      namespace + "-kubeconfig",
+					Namespace: namespace,
+				},
+			},
+			Version: "1.4.0",
+		},
+	}
+}
+
+func Test_Backfiller_Run_ConcurrentChange(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+
+	// The first patch is preceded by a change of the app, as if it was
+	// edited while waiting for the limiter.
+	var patched int
+	fakeCtrlClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(newTestApp("kiam", "eggs2")).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				patched++
+				if patched == 1 {
+					var app v1alpha1.App
+					err := c.Get(ctx, client.ObjectKeyFromObject(obj), &app)
+					if err != nil {
+						return err
+					}
+					app.Spec.ExtraConfigs = []v1alpha1.AppExtraConfig{{Kind: "secret", Name: "user-values", Namespace: "eggs2", Priority: 50}}
+					err = c.Update(ctx, &app)
+					if err != nil {
+						return err
+					}
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		CtrlClient: fakeCtrlClient,
+		K8sClient:  clientgofake.NewClientset(),
+	})
+
+	m, err := app.NewMutator(app.MutatorConfig{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Provider:  "aws",
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	b, err := New(Config{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Mutator:   m,
+		Output:    &bytes.Buffer{},
+
+		Apply: true,
+		Rate:  100,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	_, err = b.Run(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if patched != 2 {
+		t.Fatalf("patched %d times, want 2", patched)
+	}
+
+	var kiam v1alpha1.App
+	err = fakeCtrlClient.Get(ctx, client.ObjectKey{Name: "kiam", Namespace: "eggs2"}, &kiam)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	expectedExtraConfigs := []v1alpha1.AppExtraConfig{
+		{Kind: "secret", Name: "user-values", Namespace: "eggs2", Priority: 50},
+		{Kind: "configMap", Name: "eggs2-cluster-values", Namespace: "eggs2", Priority: 1},
+	}
+	if !reflect.DeepEqual(kiam.Spec.ExtraConfigs, expectedExtraConfigs) {
+		t.Fatalf("want matching extra configs \n %s", cmp.Diff(kiam.Spec.ExtraConfigs, expectedExtraConfigs))
+	}
+}
+
+func Test_Backfiller_Run_Failure(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+
+	// The app being deleted is not inspected, and patching external-dns
+	// fails without stopping the backfill of kiam.
+	deleting := newTestApp("cert-manager", "eggs2")
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleting.Finalizers = []string{"operatorkit.giantswarm.io/app-operator-app"}
+
+	fakeCtrlClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(
+			newTestApp("external-dns", "eggs2"),
+			newTestApp("kiam", "eggs2"),
+			deleting,
+		).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if obj.GetName() == "external-dns" {
+					return errors.New("patch failed")
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		CtrlClient: fakeCtrlClient,
+		K8sClient:  clientgofake.NewClientset(),
+	})
+
+	m, err := app.NewMutator(app.MutatorConfig{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Provider:  "aws",
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	var output bytes.Buffer
+	b, err := New(Config{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+		Mutator:   m,
+		Output:    &output,
+
+		Apply: true,
+		Rate:  100,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	results, err := b.Run(ctx)
+	if !IsFailed(err) {
+		t.Fatalf("error == %#v, want failedError", err)
+	}
+	if len(results) != 1 || results[0].Name != "kiam" || !results[0].Applied {
+		t.Fatalf("results == %#v, want applied kiam only", results)
+	}
+	if !strings.Contains(output.String(), "2 apps inspected, 1 apps patched, 1 apps failed") {
+		t.Fatalf("output == %q, want summary of 2 inspected apps", output.String())
+	}
+
+	var kiam v1alpha1.App
+	err = fakeCtrlClient.Get(ctx, client.ObjectKey{Name: "kiam", Namespace: "eggs2"}, &kiam)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if len(kiam.Spec.ExtraConfigs) != 1 {
+		t.Fatalf("kiam has %d extra configs, want 1", len(kiam.Spec.ExtraConfigs))
+	}
+}
@@ -0,0 +1,32 @@
+package backfill
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var failedError = &microerror.Error{
+	Kind: "failedError",
+}
+
+// IsFailed asserts failedError.
+func IsFailed(err error) bool {
+	return microerror.Cause(err) == failedError
+}
+
+var conflictError = &microerror.Error{
+	Kind: "conflictError",
+}
+
+// IsConflict asserts conflictError.
+func IsConflict(err error) bool {
+	return microerror.Cause(err) == conflictError
+}
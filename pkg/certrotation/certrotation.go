@@ -0,0 +1,408 @@
+package certrotation
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"time"
+
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/certs"
+)
+
+const (
+	// CABundleKey is the Secret key of the PEM encoded CA bundle, holding
+	// the current CA and previous CAs which did not expire yet.
+	CABundleKey = "ca-bundle.crt"
+	// CACertKey is the Secret key of the PEM encoded current CA.
+	CACertKey = "ca.crt"
+	// CAKeyKey is the Secret key of the PEM encoded private key of the
+	// current CA.
+	CAKeyKey = "ca.key"
+	// TLSCertKey is the Secret key of the PEM encoded serving certificate.
+	TLSCertKey = corev1.TLSCertKey
+	// TLSKeyKey is the Secret key of the PEM encoded private key of the
+	// serving certificate.
+	TLSKeyKey = corev1.TLSPrivateKeyKey
+)
+
+const (
+	defaultCAValidity   = 365 * 24 * time.Hour
+	defaultCertValidity = 90 * 24 * time.Hour
+	defaultInterval     = time.Minute
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+
+	// CertFile and KeyFile are the files the serving certificate is
+	// written to, to be served by the webhooks server.
+	CertFile string
+	KeyFile  string
+	// Hosts are the DNS names and IP addresses the serving certificate is
+	// valid for.
+	Hosts []string
+	// SecretName and SecretNamespace locate the Secret storing the CA and
+	// the serving certificate, shared by all replicas.
+	SecretName      string
+	SecretNamespace string
+	// WebhookConfigurationName is the name of the mutating and validating
+	// webhook configurations to inject the CA bundle into.
+	WebhookConfigurationName string
+
+	// CAValidity, CertValidity and Interval default to one year, 90 days
+	// and a minute respectively.
+	CAValidity   time.Duration
+	CertValidity time.Duration
+	Interval     time.Duration
+}
+
+// Rotator manages the CA and the serving certificate of the webhooks server
+// without cert-manager. Certificates are renewed after two thirds of their
+// validity. A renewed CA is added to the CA bundle next to the previous one,
+// so that serving certificates signed by either of them are trusted until
+// the previous CA expires.
+type Rotator struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+
+	certFile                 string
+	keyFile                  string
+	hosts                    []string
+	secretName               string
+	secretNamespace          string
+	webhookConfigurationName string
+
+	caValidity   time.Duration
+	certValidity time.Duration
+	interval     time.Duration
+
+	now func() time.Time
+}
+
+func New(config Config) (*Rotator, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.CertFile == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CertFile must not be empty", config)
+	}
+	if config.KeyFile == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.KeyFile must not be empty", config)
+	}
+	if len(config.Hosts) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Hosts must not be empty", config)
+	}
+	if config.SecretName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretName must not be empty", config)
+	}
+	if config.SecretNamespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretNamespace must not be empty", config)
+	}
+	if config.WebhookConfigurationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.WebhookConfigurationName must not be empty", config)
+	}
+
+	if config.CAValidity == 0 {
+		config.CAValidity = defaultCAValidity
+	}
+	if config.CertValidity == 0 {
+		config.CertValidity = defaultCertValidity
+	}
+	if config.Interval == 0 {
+		config.Interval = defaultInterval
+	}
+
+	r := &Rotator{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		certFile:                 config.CertFile,
+		keyFile:                  config.KeyFile,
+		hosts:                    config.Hosts,
+		secretName:               config.SecretName,
+		secretNamespace:          config.SecretNamespace,
+		webhookConfigurationName: config.WebhookConfigurationName,
+
+		caValidity:   config.CAValidity,
+		certValidity: config.CertValidity,
+		interval:     config.Interval,
+
+		now: time.Now,
+	}
+
+	return r, nil
+}
+
+// Run ensures the certificates periodically until the context is done.
+// Errors are logged and retried on the next run.
+func (r *Rotator) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.Ensure(ctx)
+			if err != nil {
+				r.logger.Errorf(ctx, err, "failed to ensure webhook certificates")
+			}
+		}
+	}
+}
+
+// Ensure renews the CA and the serving certificate stored in the Secret
+// when needed, injects the CA bundle into the webhook configurations and
+// writes the serving certificate to the files served by the webhooks
+// server. The CA bundle is injected before the serving certificate is
+// written, so the API server trusts the certificate once it is served.
+func (r *Rotator) Ensure(ctx context.Context) error {
+	// Replicas starting at the same time race for the Secret. The losers
+	// use the certificates of the winner.
+	for attempt := 1; ; attempt++ {
+		err := r.ensure(ctx)
+		if (apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err)) && attempt < 3 {
+			r.logger.Debugf(ctx, "secret %#q was updated concurrently, retrying", r.secretName)
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+}
+
+func (r *Rotator) ensure(ctx context.Context) error {
+	secret := &corev1.Secret{}
+	err := r.k8sClient.CtrlClient().Get(ctx, client.ObjectKey{Name: r.secretName, Namespace: r.secretNamespace}, secret)
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      r.secretName,
+				Namespace: r.secretNamespace,
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	data, changed, err := r.rotate(ctx, secret.Data)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if changed {
+		secret.Data = data
+
+		if secret.ResourceVersion == "" {
+			err = r.k8sClient.CtrlClient().Create(ctx, secret)
+		} else {
+			err = r.k8sClient.CtrlClient().Update(ctx, secret)
+		}
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = r.injectCABundle(ctx, data[CABundleKey])
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = writeFile(r.certFile, data[TLSCertKey])
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = writeFile(r.keyFile, data[TLSKeyKey])
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// rotate returns the Secret data with the CA and the serving certificate
+// renewed when needed, and whether the data changed.
+func (r *Rotator) rotate(ctx context.Context, data map[string][]byte) (map[string][]byte, bool, error) {
+	var err error
+	var changed bool
+
+	rotated := map[string][]byte{}
+	for k, v := range data {
+		rotated[k] = v
+	}
+
+	ca, caErr := certs.ParseCertificate(rotated[CACertKey])
+	_, keyErr := certs.ParsePrivateKey(rotated[CAKeyKey])
+	if caErr != nil || keyErr != nil || r.needsRenewal(ca) {
+		r.logger.Debugf(ctx, "renewing webhook CA")
+
+		rotated[CACertKey], rotated[CAKeyKey], err = certs.NewCA(r.secretName+"-ca", r.caValidity)
+		if err != nil {
+			return nil, false, microerror.Mask(err)
+		}
+		ca, err = certs.ParseCertificate(rotated[CACertKey])
+		if err != nil {
+			return nil, false, microerror.Mask(err)
+		}
+
+		changed = true
+	}
+
+	bundle, err := r.caBundle(ca, rotated[CABundleKey])
+	if err != nil {
+		return nil, false, microerror.Mask(err)
+	}
+	if !bytes.Equal(bundle, rotated[CABundleKey]) {
+		rotated[CABundleKey] = bundle
+		changed = true
+	}
+
+	cert, certErr := certs.ParseCertificate(rotated[TLSCertKey])
+	_, keyErr = certs.ParsePrivateKey(rotated[TLSKeyKey])
+	if certErr != nil || keyErr != nil || r.needsRenewal(cert) || cert.CheckSignatureFrom(ca) != nil || !r.validForHosts(cert) {
+		r.logger.Debugf(ctx, "renewing webhook serving certificate")
+
+		rotated[TLSCertKey], rotated[TLSKeyKey], err = certs.NewServing(rotated[CACertKey], rotated[CAKeyKey], r.hosts, r.certValidity)
+		if err != nil {
+			return nil, false, microerror.Mask(err)
+		}
+
+		changed = true
+	}
+
+	return rotated, changed, nil
+}
+
+// caBundle returns the current CA followed by the previous CAs of the
+// bundle which did not expire yet.
+func (r *Rotator) caBundle(ca *x509.Certificate, previous []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// An invalid bundle is replaced.
+	cas, _ := certs.ParseCertificates(previous)
+	for _, c := range cas {
+		if c.Equal(ca) || r.now().After(c.NotAfter) {
+			continue
+		}
+
+		err = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (r *Rotator) injectCABundle(ctx context.Context, bundle []byte) error {
+	key := client.ObjectKey{Name: r.webhookConfigurationName}
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	err := r.k8sClient.CtrlClient().Get(ctx, key, mutating)
+	if apierrors.IsNotFound(err) {
+		r.logger.Debugf(ctx, "mutating webhook configuration %#q not found", r.webhookConfigurationName)
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		var changed bool
+		for i := range mutating.Webhooks {
+			if !bytes.Equal(mutating.Webhooks[i].ClientConfig.CABundle, bundle) {
+				mutating.Webhooks[i].ClientConfig.CABundle = bundle
+				changed = true
+			}
+		}
+
+		if changed {
+			err = r.k8sClient.CtrlClient().Update(ctx, mutating)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			r.logger.Debugf(ctx, "injected CA bundle into mutating webhook configuration %#q", r.webhookConfigurationName)
+		}
+	}
+
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	err = r.k8sClient.CtrlClient().Get(ctx, key, validating)
+	if apierrors.IsNotFound(err) {
+		r.logger.Debugf(ctx, "validating webhook configuration %#q not found", r.webhookConfigurationName)
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		var changed bool
+		for i := range validating.Webhooks {
+			if !bytes.Equal(validating.Webhooks[i].ClientConfig.CABundle, bundle) {
+				validating.Webhooks[i].ClientConfig.CABundle = bundle
+				changed = true
+			}
+		}
+
+		if changed {
+			err = r.k8sClient.CtrlClient().Update(ctx, validating)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			r.logger.Debugf(ctx, "injected CA bundle into validating webhook configuration %#q", r.webhookConfigurationName)
+		}
+	}
+
+	return nil
+}
+
+// needsRenewal returns true once two thirds of the certificate validity
+// passed.
+func (r *Rotator) needsRenewal(cert *x509.Certificate) bool {
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	return r.now().After(cert.NotBefore.Add(validity * 2 / 3))
+}
+
+func (r *Rotator) validForHosts(cert *x509.Certificate) bool {
+	for _, h := range r.hosts {
+		if cert.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeFile writes the file only when its content changes, so the
+// certificate watcher of the webhooks server is not triggered needlessly.
+func writeFile(path string, data []byte) error {
+	current, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package certrotation
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/certs"
+)
+
+func Test_Rotator_Ensure(t *testing.T) {
+	tests := []struct {
+		name                string
+		elapsed             time.Duration
+		expectedCARenewed   bool
+		expectedCertRenewed bool
+		expectedCABundle    int
+	}{
+		{
+			name:             "case 0: certificates are kept",
+			elapsed:          24 * time.Hour,
+			expectedCABundle: 1,
+		},
+		{
+			name:                "case 1: expiring serving certificate is renewed",
+			elapsed:             70 * 24 * time.Hour,
+			expectedCertRenewed: true,
+			expectedCABundle:    1,
+		},
+		{
+			name:                "case 2: expiring CA is renewed and kept in the bundle",
+			elapsed:             300 * 24 * time.Hour,
+			expectedCARenewed:   true,
+			expectedCertRenewed: true,
+			expectedCABundle:    2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			fakeCtrlClient := fake.NewClientBuilder().
+				WithScheme(clientgoscheme.Scheme).
+				WithObjects(
+					&admissionregistrationv1.MutatingWebhookConfiguration{
+						ObjectMeta: metav1.ObjectMeta{Name: "app-admission-controller"},
+						Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "apps.app-admission-controller.giantswarm.io"}},
+					},
+					&admissionregistrationv1.ValidatingWebhookConfiguration{
+						ObjectMeta: metav1.ObjectMeta{Name: "app-admission-controller"},
+						Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "apps.app-admission-controller.giantswarm.io"}},
+					},
+				).
+				Build()
+
+			k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+				CtrlClient: fakeCtrlClient,
+			})
+
+			r, err := New(Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+
+				CertFile:                 filepath.Join(dir, "tls.crt"),
+				KeyFile:                  filepath.Join(dir, "tls.key"),
+				Hosts:                    []string{"app-admission-controller.giantswarm.svc"},
+				SecretName:               "app-admission-controller-certificates",
+				SecretNamespace:          "giantswarm",
+				WebhookConfigurationName: "app-admission-controller",
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			err = r.Ensure(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			initial := secretData(t, fakeCtrlClient)
+
+			now := time.Now().Add(tc.elapsed)
+			r.now = func() time.Time { return now }
+
+			err = r.Ensure(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			current := secretData(t, fakeCtrlClient)
+
+			if renewed := !bytes.Equal(initial[CACertKey], current[CACertKey]); renewed != tc.expectedCARenewed {
+				t.Fatalf("CA renewed == %t, want %t", renewed, tc.expectedCARenewed)
+			}
+			if renewed := !bytes.Equal(initial[TLSCertKey], current[TLSCertKey]); renewed != tc.expectedCertRenewed {
+				t.Fatalf("serving certificate renewed == %t, want %t", renewed, tc.expectedCertRenewed)
+			}
+
+			bundle, err := certs.ParseCertificates(current[CABundleKey])
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if len(bundle) != tc.expectedCABundle {
+				t.Fatalf("got %d CAs in the bundle, want %d", len(bundle), tc.expectedCABundle)
+			}
+
+			certFile, err := os.ReadFile(filepath.Join(dir, "tls.crt"))
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if !bytes.Equal(certFile, current[TLSCertKey]) {
+				t.Fatalf("certificate file does not match the Secret")
+			}
+
+			mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+			err = fakeCtrlClient.Get(ctx, client.ObjectKey{Name: "app-admission-controller"}, mutating)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if !bytes.Equal(mutating.Webhooks[0].ClientConfig.CABundle, current[CABundleKey]) {
+				t.Fatalf("mutating webhook CA bundle does not match the Secret")
+			}
+
+			validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+			err = fakeCtrlClient.Get(ctx, client.ObjectKey{Name: "app-admission-controller"}, validating)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if !bytes.Equal(validating.Webhooks[0].ClientConfig.CABundle, current[CABundleKey]) {
+				t.Fatalf("validating webhook CA bundle does not match the Secret")
+			}
+		})
+	}
+}
+
+func secretData(t *testing.T, ctrlClient client.Client) map[string][]byte {
+	t.Helper()
+
+	secret := &corev1.Secret{}
+	err := ctrlClient.Get(context.Background(), client.ObjectKey{Name: "app-admission-controller-certificates", Namespace: "giantswarm"}, secret)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return secret.Data
+}
@@ -0,0 +1,205 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+	authv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/app-admission-controller/v2/pkg/app"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/mutator"
+)
+
+type Config struct {
+	// K8sClient serves objects the admission logic reads. The App CRs it
+	// serves are considered current versions of the checked App CRs.
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+	Mutator   *app.Mutator
+	Output    io.Writer
+	Validator *app.Validator
+
+	UserInfo authv1.UserInfo
+}
+
+// Result describes the admission decision for a single App CR.
+type Result struct {
+	App       string                   `json:"app"`
+	Operation admissionv1.Operation    `json:"operation"`
+	Patches   []mutator.PatchOperation `json:"patches"`
+	Object    map[string]interface{}   `json:"object"`
+	Allowed   bool                     `json:"allowed"`
+	Message   string                   `json:"message,omitempty"`
+	Warnings  []string                 `json:"warnings,omitempty"`
+}
+
+// Checker runs App CRs through the mutating and validating admission logic
+// the same way the API server would, i.e. the validation sees the mutated
+// App CR.
+type Checker struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+	mutator   *app.Mutator
+	output    io.Writer
+	validator *app.Validator
+
+	userInfo authv1.UserInfo
+}
+
+func New(config Config) (*Checker, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Mutator == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Mutator must not be empty", config)
+	}
+	if config.Output == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Output must not be empty", config)
+	}
+	if config.Validator == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Validator must not be empty", config)
+	}
+
+	c := &Checker{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+		mutator:   config.Mutator,
+		output:    config.Output,
+		validator: config.Validator,
+
+		userInfo: config.UserInfo,
+	}
+
+	return c, nil
+}
+
+// Run checks all App CRs found among the given objects and prints the
+// results. It returns a rejectedError when any of the App CRs is rejected.
+func (c *Checker) Run(ctx context.Context, objs []runtime.Object) ([]Result, error) {
+	var results []Result
+	var rejected int
+
+	for _, obj := range objs {
+		cr, ok := obj.(*v1alpha1.App)
+		if !ok {
+			c.logger.Debugf(ctx, "skipping %s object", obj.GetObjectKind().GroupVersionKind().Kind)
+			continue
+		}
+
+		result, err := c.Check(ctx, *cr)
+		if err != nil {
+			return results, microerror.Mask(err)
+		}
+
+		results = append(results, result)
+		if !result.Allowed {
+			rejected++
+		}
+
+		err = c.print(result)
+		if err != nil {
+			return results, microerror.Mask(err)
+		}
+	}
+
+	if rejected > 0 {
+		return results, microerror.Maskf(rejectedError, "%d of %d apps rejected", rejected, len(results))
+	}
+
+	return results, nil
+}
+
+// Check runs a single App CR through the admission logic. The App CR is
+// updated when its current version is served by the client, otherwise it
+// is created.
+func (c *Checker) Check(ctx context.Context, cr v1alpha1.App) (Result, error) {
+	result := Result{
+		App:       fmt.Sprintf("%s/%s", cr.Namespace, cr.Name),
+		Operation: admissionv1.Create,
+	}
+
+	cr.TypeMeta = v1alpha1.NewAppTypeMeta()
+
+	var current v1alpha1.App
+	err := c.k8sClient.CtrlClient().Get(ctx, client.ObjectKeyFromObject(&cr), &current)
+	if apierrors.IsNotFound(err) {
+		// The App CR is being created.
+	} else if err != nil {
+		return Result{}, microerror.Mask(err)
+	} else {
+		result.Operation = admissionv1.Update
+		current.TypeMeta = v1alpha1.NewAppTypeMeta()
+	}
+
+	raw, err := json.Marshal(cr)
+	if err != nil {
+		return Result{}, microerror.Mask(err)
+	}
+
+	request := &admissionv1.AdmissionRequest{
+		Operation: result.Operation,
+		Object:    runtime.RawExtension{Raw: raw},
+		UserInfo:  c.userInfo,
+	}
+
+	if result.Operation == admissionv1.Update {
+		request.OldObject.Raw, err = json.Marshal(current)
+		if err != nil {
+			return Result{}, microerror.Mask(err)
+		}
+	}
+
+	// The mutating webhook entry point is used, so the result includes the
+	// patches which depend on the request, e.g. the creator annotations.
+	result.Patches, err = c.mutator.Mutate(request)
+	if err != nil {
+		result.Message = err.Error()
+		return result, nil
+	}
+
+	request.Object.Raw, err = mutator.Apply(raw, result.Patches)
+	if err != nil {
+		return Result{}, microerror.Mask(err)
+	}
+
+	err = json.Unmarshal(request.Object.Raw, &result.Object)
+	if err != nil {
+		return Result{}, microerror.Mask(err)
+	}
+
+	result.Allowed, result.Warnings, err = c.validator.ValidateWithWarnings(request)
+	if err != nil {
+		result.Allowed = false
+		result.Message = err.Error()
+	}
+
+	return result, nil
+}
+
+func (c *Checker) print(result Result) error {
+	data, err := yaml.Marshal(result)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = fmt.Fprintf(c.output, "---\n%s", data)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/fixture"
+	"github.com/giantswarm/app-admission-controller/v2/internal/recorder"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/app"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+const fixtures = `
+apiVersion: application.giantswarm.io/v1alpha1
+kind: Catalog
+metadata:
+  name: giantswarm
+  namespace: default
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: eggs2-kubeconfig
+  namespace: eggs2
+`
+
+func Test_Checker_Run(t *testing.T) {
+	tests := []struct {
+		name             string
+		currentApps      string
+		manifests        string
+		expectedAllowed  []bool
+		expectedCreator  string
+		expectedRejected bool
+	}{
+		{
+			name: "case 0: allowed app is mutated",
+			manifests: `
+apiVersion: application.giantswarm.io/v1alpha1
+kind: App
+metadata:
+  name: kiam
+  namespace: eggs2
+  labels:
+    app-operator.giantswarm.io/version: 5.5.0
+spec:
+  catalog: giantswarm
+  name: kiam
+  namespace: kube-system
+  version: 1.4.0
+`,
+			expectedAllowed: []bool{true},
+			expectedCreator: "user@example.com",
+		},
+		{
+			name: "case 1: app referencing blacklisted namespace is rejected",
+			manifests: `
+apiVersion: application.giantswarm.io/v1alpha1
+kind: App
+metadata:
+  name: kiam
+  namespace: eggs2
+  labels:
+    app-operator.giantswarm.io/version: 5.5.0
+spec:
+  catalog: giantswarm
+  name: kiam
+  namespace: kube-system
+  version: 1.4.0
+---
+apiVersion: application.giantswarm.io/v1alpha1
+kind: App
+metadata:
+  name: hello-world
+  namespace: demo0
+  labels:
+    app-operator.giantswarm.io/version: 0.0.0
+spec:
+  catalog: giantswarm
+  name: hello-world
+  namespace: demo0
+  kubeConfig:
+    inCluster: true
+  userConfig:
+    secret:
+      name: vault-token
+      namespace: giantswarm
+  version: 0.3.0
+`,
+			expectedAllowed:  []bool{true, false},
+			expectedRejected: true,
+		},
+		{
+			name: "case 2: app referencing missing catalog is rejected",
+			manifests: `
+apiVersion: application.giantswarm.io/v1alpha1
+kind: App
+metadata:
+  name: kiam
+  namespace: eggs2
+  labels:
+    app-operator.giantswarm.io/version: 5.5.0
+spec:
+  catalog: missing
+  name: kiam
+  namespace: kube-system
+  version: 1.4.0
+`,
+			expectedAllowed:  []bool{false},
+			expectedRejected: true,
+		},
+		{
+			name: "case 3: update of app found in fixtures is not matched against itself",
+			currentApps: `
+apiVersion: application.giantswarm.io/v1alpha1
+kind: App
+metadata:
+  name: kiam
+  namespace: eggs2
+  labels:
+    app-operator.giantswarm.io/version: 5.5.0
+spec:
+  catalog: giantswarm
+  name: kiam
+  namespace: kube-system
+  namespaceConfig:
+    annotations:
+      example.com/team: alpha
+  version: 1.4.0
+`,
+			manifests: `
+apiVersion: application.giantswarm.io/v1alpha1
+kind: App
+metadata:
+  name: kiam
+  namespace: eggs2
+  labels:
+    app-operator.giantswarm.io/version: 5.5.0
+spec:
+  catalog: giantswarm
+  name: kiam
+  namespace: kube-system
+  namespaceConfig:
+    annotations:
+      example.com/team: beta
+  version: 1.4.0
+`,
+			expectedAllowed: []bool{true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			objs, err := fixture.Decode([]byte(fixtures + "---" + tc.currentApps))
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			apps, err := fixture.Decode([]byte(tc.manifests))
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			k8sClient := fixture.NewClients(objs)
+
+			ins, err := secins.New(secins.Config{
+				Logger:             microloggertest.New(),
+				NamespaceBlacklist: []string{"giantswarm"},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			v, err := app.NewValidator(app.ValidatorConfig{
+				Event:     recorder.New(recorder.Config{K8sClient: k8sClient}),
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Provider:  "aws",
+				Inspector: ins,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			m, err := app.NewMutator(app.MutatorConfig{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Provider:  "aws",
+				DryRun:    true,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			c, err := New(Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+				Mutator:   m,
+				Output:    &bytes.Buffer{},
+				Validator: v,
+
+				UserInfo: authv1.UserInfo{
+					Username: "user@example.com",
+				},
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			results, err := c.Run(ctx, apps)
+			switch {
+			case err != nil && !tc.expectedRejected:
+				t.Fatalf("error == %#v, want nil", err)
+			case tc.expectedRejected && !IsRejected(err):
+				t.Fatalf("error == %#v, want rejectedError", err)
+			}
+
+			if len(results) != len(tc.expectedAllowed) {
+				t.Fatalf("got %d results, want %d", len(results), len(tc.expectedAllowed))
+			}
+			for i, r := range results {
+				if r.Allowed != tc.expectedAllowed[i] {
+					t.Fatalf("results[%d].Allowed == %t, want %t (%s)", i, r.Allowed, tc.expectedAllowed[i], r.Message)
+				}
+			}
+
+			if tc.expectedCreator != "" {
+				creator, _, err := unstructured.NestedString(results[0].Object, "metadata", "annotations", app.CreatorAnnotation)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				if creator != tc.expectedCreator {
+					t.Fatalf("creator == %#q, want %#q", creator, tc.expectedCreator)
+				}
+			}
+		})
+	}
+}
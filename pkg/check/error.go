@@ -0,0 +1,23 @@
+package check
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var rejectedError = &microerror.Error{
+	Kind: "rejectedError",
+}
+
+// IsRejected asserts rejectedError.
+func IsRejected(err error) bool {
+	return microerror.Cause(err) == rejectedError
+}
@@ -0,0 +1,167 @@
+package clientauth
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+type Config struct {
+	Logger micrologger.Logger
+
+	// CAFile is the file containing the PEM encoded CA bundle client
+	// certificates are verified with.
+	CAFile string
+	// AllowedSubjects are the common names of client certificates allowed
+	// to call the wrapped handlers. Any verified client is allowed when
+	// empty.
+	AllowedSubjects []string
+}
+
+// Authenticator verifies client certificates of requests, e.g. to make sure
+// that only the Kubernetes API server calls the admission webhooks. The CA
+// bundle is reloaded when its file changes.
+type Authenticator struct {
+	logger micrologger.Logger
+
+	caFile          string
+	allowedSubjects map[string]bool
+
+	mutex sync.RWMutex
+	pool  *x509.CertPool
+}
+
+func New(config Config) (*Authenticator, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.CAFile == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CAFile must not be empty", config)
+	}
+
+	a := &Authenticator{
+		logger: config.Logger,
+
+		caFile:          config.CAFile,
+		allowedSubjects: map[string]bool{},
+	}
+
+	for _, s := range config.AllowedSubjects {
+		a.allowedSubjects[s] = true
+	}
+
+	err := a.load()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return a, nil
+}
+
+// Watch reloads the CA bundle whenever the directory containing its file
+// changes, until the context is done. The directory is watched rather than
+// the file, so that files of mounted ConfigMaps and Secrets, which are
+// replaced by swapping symlinks, keep being watched. Invalid CA bundles are
+// logged and the previous one is kept.
+func (a *Authenticator) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = watcher.Add(filepath.Dir(a.caFile))
+	if err != nil {
+		_ = watcher.Close()
+		return microerror.Mask(err)
+	}
+
+	go func() {
+		defer watcher.Close() //nolint:errcheck
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-watcher.Events:
+				err := a.load()
+				if err != nil {
+					a.logger.Errorf(ctx, err, "unable to reload client CA file %#q", a.caFile)
+				}
+			case err := <-watcher.Errors:
+				a.logger.Errorf(ctx, err, "unable to watch client CA file %#q", a.caFile)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Handler only passes requests with a client certificate verified by the
+// CA bundle, and with an allowed subject, to the given handler.
+func (a *Authenticator) Handler(next http.Handler) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		ctx := context.Background()
+
+		if request.TLS == nil || len(request.TLS.PeerCertificates) == 0 {
+			a.logger.Errorf(ctx, nil, "rejected request to %s from %s without client certificate", request.URL.Path, request.RemoteAddr)
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		cert := request.TLS.PeerCertificates[0]
+
+		intermediates := x509.NewCertPool()
+		for _, c := range request.TLS.PeerCertificates[1:] {
+			intermediates.AddCert(c)
+		}
+
+		a.mutex.RLock()
+		roots := a.pool
+		a.mutex.RUnlock()
+
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			a.logger.Errorf(ctx, err, "rejected request to %s from %s with unverified client certificate %#q", request.URL.Path, request.RemoteAddr, cert.Subject.CommonName)
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if len(a.allowedSubjects) > 0 && !a.allowedSubjects[cert.Subject.CommonName] {
+			a.logger.Errorf(ctx, nil, "rejected request to %s from %s with client certificate subject %#q not allowed", request.URL.Path, request.RemoteAddr, cert.Subject.CommonName)
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	}
+}
+
+func (a *Authenticator) load() error {
+	data, err := os.ReadFile(a.caFile)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return microerror.Maskf(invalidCAError, "no certificates found in %#q", a.caFile)
+	}
+
+	a.mutex.Lock()
+	a.pool = pool
+	a.mutex.Unlock()
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package clientauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/certs"
+)
+
+func Test_Authenticator_Handler(t *testing.T) {
+	caCert, caKey, err := certs.NewCA("client-ca", time.Hour)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	otherCACert, otherCAKey, err := certs.NewCA("other-ca", time.Hour)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	apiserver := newClientCert(t, caCert, caKey, "kube-apiserver")
+	other := newClientCert(t, caCert, caKey, "other")
+	untrusted := newClientCert(t, otherCACert, otherCAKey, "kube-apiserver")
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	err = os.WriteFile(caFile, caCert, 0600)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	tests := []struct {
+		name            string
+		allowedSubjects []string
+		peerCertificate *x509.Certificate
+		expectedStatus  int
+	}{
+		{
+			name:            "case 0: allowed subject",
+			allowedSubjects: []string{"kube-apiserver"},
+			peerCertificate: apiserver,
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:            "case 1: any verified subject",
+			peerCertificate: other,
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:            "case 2: subject not allowed",
+			allowedSubjects: []string{"kube-apiserver"},
+			peerCertificate: other,
+			expectedStatus:  http.StatusForbidden,
+		},
+		{
+			name:            "case 3: certificate signed by another CA",
+			peerCertificate: untrusted,
+			expectedStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:           "case 4: no certificate",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := New(Config{
+				Logger: microloggertest.New(),
+
+				CAFile:          caFile,
+				AllowedSubjects: tc.allowedSubjects,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/validate/app", nil)
+			request.TLS = &tls.ConnectionState{}
+			if tc.peerCertificate != nil {
+				request.TLS.PeerCertificates = []*x509.Certificate{tc.peerCertificate}
+			}
+
+			recorder := httptest.NewRecorder()
+			a.Handler(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(http.StatusOK)
+			})).ServeHTTP(recorder, request)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Fatalf("status == %d, want %d", recorder.Code, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func newClientCert(t *testing.T, caCert, caKey []byte, commonName string) *x509.Certificate {
+	t.Helper()
+
+	certPEM, _, err := certs.NewClient(caCert, caKey, commonName, time.Hour)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	cert, err := certs.ParseCertificate(certPEM)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return cert
+}
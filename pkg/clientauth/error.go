@@ -0,0 +1,23 @@
+package clientauth
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidCAError = &microerror.Error{
+	Kind: "invalidCAError",
+}
+
+// IsInvalidCA asserts invalidCAError.
+func IsInvalidCA(err error) bool {
+	return microerror.Cause(err) == invalidCAError
+}
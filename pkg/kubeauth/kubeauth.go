@@ -0,0 +1,254 @@
+package kubeauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+const (
+	defaultAuthenticationTTL = 2 * time.Minute
+	defaultAuthorizationTTL  = time.Minute
+
+	// maxCacheEntries bounds each of the caches, the least recently used
+	// entries are evicted first.
+	maxCacheEntries = 1024
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+
+	// Audiences are the audiences bearer tokens must be valid for. The API
+	// server audiences are used when empty.
+	Audiences []string
+	// ResourceAttributes are the attributes requests are authorised
+	// against, with the verb derived from the HTTP method. The request
+	// path is authorised as a non-resource URL when nil.
+	ResourceAttributes *authorizationv1.ResourceAttributes
+
+	// AuthenticationTTL and AuthorizationTTL are how long successful
+	// TokenReviews and SubjectAccessReview decisions are cached, defaulting
+	// to two minutes and a minute respectively.
+	AuthenticationTTL time.Duration
+	AuthorizationTTL  time.Duration
+}
+
+// Authorizer authenticates requests with bearer tokens using TokenReviews
+// and authorises them using SubjectAccessReviews, the same way
+// kube-rbac-proxy does. Reviews are cached for a short time, so that
+// every scrape does not reach the API server.
+type Authorizer struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+
+	audiences          []string
+	resourceAttributes *authorizationv1.ResourceAttributes
+
+	authenticationTTL time.Duration
+	authorizationTTL  time.Duration
+
+	// authentications are keyed by token hashes, so that tokens are not
+	// kept in memory, and authorizations by SubjectAccessReview specs,
+	// which hold both the user and the attributes.
+	authentications *cache.LRUExpireCache
+	authorizations  *cache.LRUExpireCache
+
+	now func() time.Time
+}
+
+func New(config Config) (*Authorizer, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.AuthenticationTTL == 0 {
+		config.AuthenticationTTL = defaultAuthenticationTTL
+	}
+	if config.AuthorizationTTL == 0 {
+		config.AuthorizationTTL = defaultAuthorizationTTL
+	}
+
+	a := &Authorizer{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		audiences:          config.Audiences,
+		resourceAttributes: config.ResourceAttributes,
+
+		authenticationTTL: config.AuthenticationTTL,
+		authorizationTTL:  config.AuthorizationTTL,
+
+		now: time.Now,
+	}
+
+	// The caches read the time through the Authorizer, so that tests can
+	// move it forward.
+	now := clock(func() time.Time { return a.now() })
+	a.authentications = cache.NewLRUExpireCacheWithClock(maxCacheEntries, now)
+	a.authorizations = cache.NewLRUExpireCacheWithClock(maxCacheEntries, now)
+
+	return a, nil
+}
+
+// Handler only passes authenticated and authorised requests to the given
+// handler.
+func (a *Authorizer) Handler(next http.Handler) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		ctx := request.Context()
+
+		user, err := a.authenticate(ctx, request)
+		if IsUnauthenticated(err) {
+			a.logger.Debugf(ctx, "rejected request to %s from %s: %s", request.URL.Path, request.RemoteAddr, err.Error())
+			http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			a.logger.Errorf(ctx, err, "unable to authenticate request to %s", request.URL.Path)
+			http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		allowed, reason, err := a.authorize(ctx, request, user)
+		if err != nil {
+			a.logger.Errorf(ctx, err, "unable to authorise request to %s", request.URL.Path)
+			http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			a.logger.Debugf(ctx, "rejected request to %s from user %#q: %s", request.URL.Path, user.Username, reason)
+			http.Error(writer, fmt.Sprintf("Forbidden (user=%s, verb=%s, %s)", user.Username, verb(request), a.describe(request)), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	}
+}
+
+func (a *Authorizer) authenticate(ctx context.Context, request *http.Request) (authenticationv1.UserInfo, error) {
+	token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+	if !ok || token == "" {
+		return authenticationv1.UserInfo{}, microerror.Maskf(unauthenticatedError, "missing bearer token")
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	if user, ok := a.authentications.Get(key); ok {
+		return user.(authenticationv1.UserInfo), nil
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: a.audiences,
+		},
+	}
+
+	review, err := a.k8sClient.K8sClient().AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return authenticationv1.UserInfo{}, microerror.Mask(err)
+	}
+
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, microerror.Maskf(unauthenticatedError, "invalid bearer token: %s", review.Status.Error)
+	}
+
+	a.authentications.Add(key, review.Status.User, a.authenticationTTL)
+
+	return review.Status.User, nil
+}
+
+func (a *Authorizer) authorize(ctx context.Context, request *http.Request, user authenticationv1.UserInfo) (bool, string, error) {
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+		},
+	}
+
+	if len(user.Extra) > 0 {
+		review.Spec.Extra = map[string]authorizationv1.ExtraValue{}
+		for k, v := range user.Extra {
+			review.Spec.Extra[k] = authorizationv1.ExtraValue(v)
+		}
+	}
+
+	if a.resourceAttributes != nil {
+		attributes := *a.resourceAttributes
+		attributes.Verb = verb(request)
+		review.Spec.ResourceAttributes = &attributes
+	} else {
+		review.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+			Path: request.URL.Path,
+			Verb: verb(request),
+		}
+	}
+
+	data, err := json.Marshal(review.Spec)
+	if err != nil {
+		return false, "", microerror.Mask(err)
+	}
+	key := string(data)
+	if cached, ok := a.authorizations.Get(key); ok {
+		status := cached.(authorizationv1.SubjectAccessReviewStatus)
+		return status.Allowed, status.Reason, nil
+	}
+
+	review, err = a.k8sClient.K8sClient().AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", microerror.Mask(err)
+	}
+
+	a.authorizations.Add(key, review.Status, a.authorizationTTL)
+
+	return review.Status.Allowed, review.Status.Reason, nil
+}
+
+// clock reads the time of the Authorizer for its caches.
+type clock func() time.Time
+
+func (c clock) Now() time.Time {
+	return c()
+}
+
+func (a *Authorizer) describe(request *http.Request) string {
+	if a.resourceAttributes == nil {
+		return fmt.Sprintf("path=%s", request.URL.Path)
+	}
+
+	r := a.resourceAttributes
+	return fmt.Sprintf("resource=%s, subresource=%s, namespace=%s, name=%s", r.Resource, r.Subresource, r.Namespace, r.Name)
+}
+
+// verb maps the HTTP method to the Kubernetes API verb.
+func verb(request *http.Request) string {
+	switch request.Method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "get"
+	}
+}
@@ -0,0 +1,229 @@
+package kubeauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func Test_Authorizer_Handler(t *testing.T) {
+	tests := []struct {
+		name               string
+		token              string
+		resourceAttributes *authorizationv1.ResourceAttributes
+		expectedStatus     int
+		expectedReview     string
+	}{
+		{
+			name:           "case 0: authorised non-resource URL",
+			token:          "prometheus",
+			expectedStatus: http.StatusOK,
+			expectedReview: "get /metrics",
+		},
+		{
+			name:  "case 1: authorised resource",
+			token: "prometheus",
+			resourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: "giantswarm",
+				Resource:  "services",
+				Name:      "app-admission-controller",
+			},
+			expectedStatus: http.StatusOK,
+			expectedReview: "get services",
+		},
+		{
+			name:           "case 2: unauthorised user",
+			token:          "other",
+			expectedStatus: http.StatusForbidden,
+			expectedReview: "get /metrics",
+		},
+		{
+			name:           "case 3: invalid token",
+			token:          "invalid",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "case 4: missing token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var review string
+
+			clientset := clientgofake.NewClientset()
+			clientset.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				r := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				if r.Spec.Token != "invalid" {
+					r.Status.Authenticated = true
+					r.Status.User = authenticationv1.UserInfo{Username: r.Spec.Token}
+				}
+				return true, r, nil
+			})
+			clientset.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				r := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				if r.Spec.ResourceAttributes != nil {
+					review = r.Spec.ResourceAttributes.Verb + " " + r.Spec.ResourceAttributes.Resource
+				} else {
+					review = r.Spec.NonResourceAttributes.Verb + " " + r.Spec.NonResourceAttributes.Path
+				}
+				r.Status.Allowed = r.Spec.User == "prometheus"
+				return true, r, nil
+			})
+
+			a, err := New(Config{
+				K8sClient: k8sclienttest.NewClients(k8sclienttest.ClientsConfig{K8sClient: clientset}),
+				Logger:    microloggertest.New(),
+
+				ResourceAttributes: tc.resourceAttributes,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+
+			recorder := httptest.NewRecorder()
+			a.Handler(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(http.StatusOK)
+			})).ServeHTTP(recorder, request)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Fatalf("status == %d, want %d", recorder.Code, tc.expectedStatus)
+			}
+			if review != tc.expectedReview {
+				t.Fatalf("review == %#q, want %#q", review, tc.expectedReview)
+			}
+		})
+	}
+}
+
+func Test_Authorizer_Handler_Cache(t *testing.T) {
+	tests := []struct {
+		name                         string
+		tokens                       []string
+		paths                        []string
+		elapsed                      time.Duration
+		expectedTokenReviews         int
+		expectedSubjectAccessReviews int
+	}{
+		{
+			name:                         "case 0: reviews are cached",
+			tokens:                       []string{"prometheus", "prometheus"},
+			paths:                        []string{"/metrics", "/metrics"},
+			expectedTokenReviews:         1,
+			expectedSubjectAccessReviews: 1,
+		},
+		{
+			name:                         "case 1: denied decisions are cached",
+			tokens:                       []string{"other", "other"},
+			paths:                        []string{"/metrics", "/metrics"},
+			expectedTokenReviews:         1,
+			expectedSubjectAccessReviews: 1,
+		},
+		{
+			name:                         "case 2: other tokens are reviewed",
+			tokens:                       []string{"prometheus", "other"},
+			paths:                        []string{"/metrics", "/metrics"},
+			expectedTokenReviews:         2,
+			expectedSubjectAccessReviews: 2,
+		},
+		{
+			name:                         "case 3: other paths are authorised",
+			tokens:                       []string{"prometheus", "prometheus"},
+			paths:                        []string{"/metrics", "/other"},
+			expectedTokenReviews:         1,
+			expectedSubjectAccessReviews: 2,
+		},
+		{
+			name:                 "case 4: invalid tokens are not cached",
+			tokens:               []string{"invalid", "invalid"},
+			paths:                []string{"/metrics", "/metrics"},
+			expectedTokenReviews: 2,
+		},
+		{
+			name:                         "case 5: expired decisions are reviewed again",
+			tokens:                       []string{"prometheus", "prometheus"},
+			paths:                        []string{"/metrics", "/metrics"},
+			elapsed:                      90 * time.Second,
+			expectedTokenReviews:         1,
+			expectedSubjectAccessReviews: 2,
+		},
+		{
+			name:                         "case 6: expired tokens are reviewed again",
+			tokens:                       []string{"prometheus", "prometheus"},
+			paths:                        []string{"/metrics", "/metrics"},
+			elapsed:                      3 * time.Minute,
+			expectedTokenReviews:         2,
+			expectedSubjectAccessReviews: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var tokenReviews, subjectAccessReviews int
+
+			clientset := clientgofake.NewClientset()
+			clientset.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				tokenReviews++
+				r := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				if r.Spec.Token != "invalid" {
+					r.Status.Authenticated = true
+					r.Status.User = authenticationv1.UserInfo{Username: r.Spec.Token}
+				}
+				return true, r, nil
+			})
+			clientset.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				subjectAccessReviews++
+				r := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				r.Status.Allowed = r.Spec.User == "prometheus"
+				return true, r, nil
+			})
+
+			a, err := New(Config{
+				K8sClient: k8sclienttest.NewClients(k8sclienttest.ClientsConfig{K8sClient: clientset}),
+				Logger:    microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			now := time.Now()
+			a.now = func() time.Time { return now }
+
+			handler := a.Handler(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(http.StatusOK)
+			}))
+
+			for i := range tc.tokens {
+				if i > 0 {
+					now = now.Add(tc.elapsed)
+				}
+
+				request := httptest.NewRequest(http.MethodGet, tc.paths[i], nil)
+				request.Header.Set("Authorization", "Bearer "+tc.tokens[i])
+				handler.ServeHTTP(httptest.NewRecorder(), request)
+			}
+
+			if tokenReviews != tc.expectedTokenReviews {
+				t.Fatalf("token reviews == %d, want %d", tokenReviews, tc.expectedTokenReviews)
+			}
+			if subjectAccessReviews != tc.expectedSubjectAccessReviews {
+				t.Fatalf("subject access reviews == %d, want %d", subjectAccessReviews, tc.expectedSubjectAccessReviews)
+			}
+		})
+	}
+}
@@ -7,6 +7,12 @@ import (
 const (
 	metricNamespace = "app_admission_controller" //nolint:gosec
 	metricSubsystem = "webhook"
+
+	auditSubsystem      = "audit"
+	breakGlassSubsystem = "break_glass"
+	policySubsystem     = "policy"
+	ruleSubsystem       = "rule"
+	shadowSubsystem     = "shadow"
 )
 
 var (
@@ -51,6 +57,99 @@ var (
 	}, labels)
 )
 
+var (
+	AuditApps = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Subsystem: auditSubsystem,
+		Name:      "apps",
+		Help:      "Number of apps inspected during the last audit run",
+	})
+	AuditDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Subsystem: auditSubsystem,
+		Name:      "last_run_duration_seconds",
+		Help:      "Duration of the last audit run",
+	})
+	AuditLastRun = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Subsystem: auditSubsystem,
+		Name:      "last_run_timestamp_seconds",
+		Help:      "Timestamp of the last finished audit run",
+	})
+	AuditViolations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Subsystem: auditSubsystem,
+		Name:      "violations",
+		Help:      "Number of apps violating admission rules found during the last audit run",
+	}, []string{"check", "namespace"})
+)
+
+var (
+	PolicyAuditViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: policySubsystem,
+		Name:      "audit_violations_total",
+		Help:      "Total number of requests admitted despite violating policies in the audit mode",
+	}, []string{"namespace"})
+)
+
+var (
+	RuleEvaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: ruleSubsystem,
+		Name:      "evaluations_total",
+		Help:      "Total number of evaluations of validation rules by result, one of allowed, denied, error and skipped",
+	}, []string{"rule", "result"})
+	RuleDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: ruleSubsystem,
+		Name:      "decisions_total",
+		Help:      "Total number of rule violations by the mode they were handled in, one of enforce, warn, audit and dry-run",
+	}, []string{"rule", "mode"})
+	RuleEvaluationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricNamespace,
+		Subsystem: ruleSubsystem,
+		Name:      "evaluation_duration_seconds",
+		Help:      "Duration of evaluations of validation rules",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1},
+	}, []string{"rule"})
+)
+
+var (
+	ShadowEvaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: shadowSubsystem,
+		Name:      "evaluations_total",
+		Help:      "Total number of requests evaluated against the candidate configuration by result, one of agreed, disagreed and skipped",
+	}, []string{"result"})
+	ShadowDisagreements = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: shadowSubsystem,
+		Name:      "disagreements_total",
+		Help:      "Total number of requests the candidate configuration decided differently than the live one",
+	}, []string{"namespace", "live", "candidate"})
+)
+
+var (
+	BreakGlassBypasses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: breakGlassSubsystem,
+		Name:      "bypasses_total",
+		Help:      "Total number of rule violations bypassed with break-glass tokens",
+	}, []string{"rule"})
+	BreakGlassRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: breakGlassSubsystem,
+		Name:      "rejections_total",
+		Help:      "Total number of requests rejected for invalid or expired break-glass tokens",
+	}, []string{"namespace"})
+)
+
 func init() {
 	prometheus.MustRegister(TotalRequests, InvalidRequests, RejectedRequests, SuccessfulRequests, DurationRequests)
+	prometheus.MustRegister(AuditApps, AuditDuration, AuditLastRun, AuditViolations)
+	prometheus.MustRegister(PolicyAuditViolations)
+	prometheus.MustRegister(RuleEvaluations, RuleEvaluationDuration, RuleDecisions)
+	prometheus.MustRegister(ShadowEvaluations, ShadowDisagreements)
+	prometheus.MustRegister(BreakGlassBypasses, BreakGlassRejections)
 }
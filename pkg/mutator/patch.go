@@ -1,5 +1,13 @@
 package mutator
 
+import (
+	"encoding/json"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/giantswarm/microerror"
+)
+
 // PatchOperation specifies one JSONPatch operation.
 // See [RFC6902](https://tools.ietf.org/html/rfc6902) for details.
 type PatchOperation struct {
@@ -35,3 +43,79 @@ func PatchAdd(path string, value interface{}) PatchOperation {
 		Value:     value,
 	}
 }
+
+// PatchRemove creates a patch operation of type "remove".
+func PatchRemove(path string) PatchOperation {
+	return PatchOperation{
+		Operation: "remove",
+		Path:      path,
+	}
+}
+
+// PatchTest creates a patch operation of type "test", failing the whole
+// patch unless the value at the path equals the given one.
+func PatchTest(path string, value interface{}) PatchOperation {
+	return PatchOperation{
+		Operation: "test",
+		Path:      path,
+		Value:     value,
+	}
+}
+
+// Apply applies the given patch operations to the JSON document and returns
+// the patched document.
+func Apply(doc []byte, patches []PatchOperation) ([]byte, error) {
+	if len(patches) == 0 {
+		return doc, nil
+	}
+
+	data, err := json.Marshal(patches)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	patch, err := jsonpatch.DecodePatch(data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return patched, nil
+}
+
+// Effective returns the subset of patch operations that actually change the
+// given JSON document. Operations that only prepare empty maps or lists for
+// subsequent operations are skipped as they carry no information on their own.
+func Effective(doc []byte, patches []PatchOperation) ([]PatchOperation, error) {
+	var result []PatchOperation
+
+	current := doc
+	for _, p := range patches {
+		patched, err := Apply(current, []PatchOperation{p})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if !isEmptyValue(p.Value) && !jsonpatch.Equal(current, patched) {
+			result = append(result, p)
+		}
+
+		current = patched
+	}
+
+	return result, nil
+}
+
+func isEmptyValue(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	}
+
+	return false
+}
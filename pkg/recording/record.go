@@ -0,0 +1,154 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// Entry is a single recorded AdmissionReview request and response pair,
+// stored as one line of a JSONL file.
+type Entry struct {
+	Path     string                         `json:"path"`
+	Time     time.Time                      `json:"time"`
+	Request  *admissionv1.AdmissionRequest  `json:"request"`
+	Response *admissionv1.AdmissionResponse `json:"response"`
+}
+
+type Config struct {
+	Logger micrologger.Logger
+	Output io.Writer
+
+	// SampleRate is the fraction of requests to record, between 0 and 1.
+	SampleRate float64
+}
+
+// Recorder writes sampled AdmissionReview requests and responses of the
+// wrapped handlers, with Secret data redacted.
+type Recorder struct {
+	logger micrologger.Logger
+
+	mutex  sync.Mutex
+	output io.Writer
+
+	sampleRate float64
+}
+
+func New(config Config) (*Recorder, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Output == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Output must not be empty", config)
+	}
+
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SampleRate must be between 0 and 1", config)
+	}
+
+	r := &Recorder{
+		logger: config.Logger,
+		output: config.Output,
+
+		sampleRate: config.SampleRate,
+	}
+
+	return r, nil
+}
+
+// Handler records sampled requests served by the given handler.
+func (r *Recorder) Handler(next http.Handler) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if r.sampleRate < 1 && rand.Float64() >= r.sampleRate { //nolint:gosec
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		ctx := context.Background()
+
+		data, err := io.ReadAll(request.Body)
+		if err != nil {
+			r.logger.Errorf(ctx, err, "unable to read request")
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewReader(data))
+
+		rw := &responseWriter{ResponseWriter: writer}
+		next.ServeHTTP(rw, request)
+
+		err = r.record(request.URL.Path, data, rw.body.Bytes())
+		if err != nil {
+			r.logger.Errorf(ctx, err, "unable to record admission review for %s", request.URL.Path)
+		}
+	}
+}
+
+func (r *Recorder) record(path string, requestData, responseData []byte) error {
+	var requestReview, responseReview admissionv1.AdmissionReview
+
+	// Requests rejected before reaching the admission logic, e.g. with an
+	// invalid content type, have no response to record.
+	if len(responseData) == 0 {
+		return nil
+	}
+
+	err := json.Unmarshal(requestData, &requestReview)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = json.Unmarshal(responseData, &responseReview)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if requestReview.Request == nil || responseReview.Response == nil {
+		return nil
+	}
+
+	err = redact(requestReview.Request)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	line, err := json.Marshal(Entry{
+		Path:     path,
+		Time:     time.Now().UTC(),
+		Request:  requestReview.Request,
+		Response: responseReview.Response,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, err = r.output.Write(append(line, '\n'))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// responseWriter keeps a copy of the response body.
+type responseWriter struct {
+	http.ResponseWriter
+
+	body bytes.Buffer
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
@@ -0,0 +1,258 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/breakglass"
+)
+
+func Test_Recorder_Replay(t *testing.T) {
+	request := &admissionv1.AdmissionRequest{
+		UID:       "6b2b7a6e-0f0e-4f4e-9d3c-1b2a3c4d5e6f",
+		Operation: admissionv1.Create,
+		Object: runtime.RawExtension{
+			Raw: []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"token"},"data":{"token":"c2VjcmV0"},"stringData":{"password":"secret"}}`),
+		},
+	}
+
+	var output bytes.Buffer
+
+	r, err := New(Config{
+		Logger:     microloggertest.New(),
+		Output:     &output,
+		SampleRate: 1,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	recorded := newTestHandler(true, "")
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate/app", r.Handler(recorded))
+
+	review, err := json.Marshal(admissionv1.AdmissionReview{Request: request})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/validate/app", bytes.NewReader(review))
+	req.Header.Set("Content-Type", "application/json")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	if strings.Contains(output.String(), "c2VjcmV0") || strings.Contains(output.String(), `"secret"`) {
+		t.Fatalf("recording contains Secret data: %s", output.String())
+	}
+
+	entries, err := Read(&output)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+
+	tests := []struct {
+		name                string
+		handler             http.Handler
+		expectedDifferences []string
+	}{
+		{
+			name:    "case 0: same responses",
+			handler: newTestHandler(true, ""),
+		},
+		{
+			name:                "case 1: different decision",
+			handler:             newTestHandler(false, "security violation error"),
+			expectedDifferences: []string{"allowed", "message"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("/validate/app", tc.handler)
+
+			differences, err := Replay(mux, entries, io.Discard)
+			switch {
+			case len(tc.expectedDifferences) == 0 && err != nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case len(tc.expectedDifferences) > 0 && !IsMismatch(err):
+				t.Fatalf("error == %#v, want mismatchError", err)
+			}
+
+			var fields []string
+			for _, d := range differences {
+				fields = append(fields, d.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tc.expectedDifferences, ",") {
+				t.Fatalf("got %v differences, want %v", fields, tc.expectedDifferences)
+			}
+		})
+	}
+}
+
+func Test_Recorder_Handler_BreakGlass(t *testing.T) {
+	token := "eyJhbGciOiJIUzI1NiJ9.c2lnbmVk.dG9rZW4"
+
+	newApp := func(version string) []byte {
+		app := map[string]interface{}{
+			"apiVersion": "application.giantswarm.io/v1alpha1",
+			"kind":       "App",
+			"metadata": map[string]interface{}{
+				"name":      "hello-world",
+				"namespace": "org-acme",
+				"annotations": map[string]interface{}{
+					breakglass.Annotation: token,
+				},
+			},
+			"spec": map[string]interface{}{
+				"version": version,
+			},
+		}
+
+		applied, err := json.Marshal(app)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		app["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})[corev1.LastAppliedConfigAnnotation] = string(applied) + "\n"
+
+		data, err := json.Marshal(app)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		return data
+	}
+
+	request := &admissionv1.AdmissionRequest{
+		UID:       "0f8e3c1a-6a1b-4c0e-9a57-2d4b1c3e5f70",
+		Operation: admissionv1.Update,
+		Object:    runtime.RawExtension{Raw: newApp("0.3.1")},
+		OldObject: runtime.RawExtension{Raw: newApp("0.3.0")},
+	}
+
+	var output bytes.Buffer
+
+	r, err := New(Config{
+		Logger:     microloggertest.New(),
+		Output:     &output,
+		SampleRate: 1,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate/app", r.Handler(newTestHandler(true, "")))
+
+	review, err := json.Marshal(admissionv1.AdmissionReview{Request: request})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/validate/app", bytes.NewReader(review))
+	req.Header.Set("Content-Type", "application/json")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	if output.Len() == 0 {
+		t.Fatalf("nothing recorded")
+	}
+	if strings.Contains(output.String(), token) {
+		t.Fatalf("recording contains break-glass token: %s", output.String())
+	}
+}
+
+func Test_redact(t *testing.T) {
+	tests := []struct {
+		name              string
+		object            string
+		oldObject         string
+		expectedObject    string
+		expectedOldObject string
+	}{
+		{
+			name:           "case 0: secret data is redacted",
+			object:         `{"kind":"Secret","data":{"token":"c2VjcmV0"},"stringData":{"password":"secret"}}`,
+			expectedObject: `{"data":{"token":"UkVEQUNURUQ="},"kind":"Secret","stringData":{"password":"REDACTED"}}`,
+		},
+		{
+			name:              "case 1: break-glass annotation is redacted in both objects",
+			object:            `{"kind":"App","metadata":{"annotations":{"app-admission-controller.giantswarm.io/break-glass":"token","foo":"bar"}}}`,
+			oldObject:         `{"kind":"App","metadata":{"annotations":{"app-admission-controller.giantswarm.io/break-glass":"old-token"}}}`,
+			expectedObject:    `{"kind":"App","metadata":{"annotations":{"app-admission-controller.giantswarm.io/break-glass":"REDACTED","foo":"bar"}}}`,
+			expectedOldObject: `{"kind":"App","metadata":{"annotations":{"app-admission-controller.giantswarm.io/break-glass":"REDACTED"}}}`,
+		},
+		{
+			name:           "case 2: break-glass annotation is redacted in last applied configuration",
+			object:         `{"kind":"App","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"kind\":\"App\",\"metadata\":{\"annotations\":{\"app-admission-controller.giantswarm.io/break-glass\":\"token\"}}}\n"}}}`,
+			expectedObject: `{"kind":"App","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"kind\":\"App\",\"metadata\":{\"annotations\":{\"app-admission-controller.giantswarm.io/break-glass\":\"REDACTED\"}}}"}}}`,
+		},
+		{
+			name:           "case 3: unparsable last applied configuration is dropped",
+			object:         `{"kind":"App","metadata":{"annotations":{"foo":"bar","kubectl.kubernetes.io/last-applied-configuration":"{"}}}`,
+			expectedObject: `{"kind":"App","metadata":{"annotations":{"foo":"bar"}}}`,
+		},
+		{
+			name:           "case 4: secret data is redacted in last applied configuration",
+			object:         `{"kind":"Secret","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"kind\":\"Secret\",\"stringData\":{\"password\":\"secret\"}}\n"}},"stringData":{"password":"secret"}}`,
+			expectedObject: `{"kind":"Secret","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"kind\":\"Secret\",\"stringData\":{\"password\":\"REDACTED\"}}"}},"stringData":{"password":"REDACTED"}}`,
+		},
+		{
+			name:           "case 5: app without break-glass annotation is left as is",
+			object:         `{"kind":"App", "metadata":{"annotations":{"foo":"bar"}}}`,
+			expectedObject: `{"kind":"App", "metadata":{"annotations":{"foo":"bar"}}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			request := &admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: []byte(tc.object)},
+				OldObject: runtime.RawExtension{Raw: []byte(tc.oldObject)},
+			}
+
+			err := redact(request)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if string(request.Object.Raw) != tc.expectedObject {
+				t.Fatalf("object == %s, want %s", request.Object.Raw, tc.expectedObject)
+			}
+			if string(request.OldObject.Raw) != tc.expectedOldObject {
+				t.Fatalf("oldObject == %s, want %s", request.OldObject.Raw, tc.expectedOldObject)
+			}
+		})
+	}
+}
+
+func newTestHandler(allowed bool, message string) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var review admissionv1.AdmissionReview
+		err := json.NewDecoder(request.Body).Decode(&review)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		response := &admissionv1.AdmissionResponse{
+			UID:     review.Request.UID,
+			Allowed: allowed,
+		}
+		if message != "" {
+			response.Result = &metav1.Status{Message: message}
+		}
+
+		_ = json.NewEncoder(writer).Encode(admissionv1.AdmissionReview{Response: response})
+	}
+}
@@ -0,0 +1,120 @@
+package recording
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/giantswarm/microerror"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/breakglass"
+)
+
+const (
+	redactedValue = "REDACTED"
+)
+
+// redactedAnnotations hold credentials, e.g. signed break-glass tokens which
+// could be reused while they are valid.
+var redactedAnnotations = []string{
+	breakglass.Annotation,
+}
+
+// redact replaces values of Secrets and of annotations holding credentials
+// found in the request objects, so that recordings can be shared.
+func redact(request *admissionv1.AdmissionRequest) error {
+	for _, raw := range []*runtime.RawExtension{&request.Object, &request.OldObject} {
+		if len(raw.Raw) == 0 {
+			continue
+		}
+
+		var obj map[string]interface{}
+		err := json.Unmarshal(raw.Raw, &obj)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if !redactObject(obj) {
+			continue
+		}
+
+		raw.Raw, err = json.Marshal(obj)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		raw.Object = nil
+	}
+
+	return nil
+}
+
+// redactObject redacts the object in place and reports whether anything was
+// redacted.
+func redactObject(obj map[string]interface{}) bool {
+	redacted := redactAnnotations(obj)
+	if obj["kind"] == "Secret" {
+		redacted = redactSecret(obj) || redacted
+	}
+
+	return redacted
+}
+
+func redactAnnotations(obj map[string]interface{}) bool {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	var redacted bool
+	for _, k := range redactedAnnotations {
+		if _, ok := annotations[k]; ok {
+			annotations[k] = redactedValue
+			redacted = true
+		}
+	}
+
+	// kubectl apply keeps a copy of the applied object, e.g. the plain
+	// text data of Secrets, which is redacted the same way. It is dropped when it
+	// can't be parsed, so that nothing is leaked.
+	if applied, ok := annotations[corev1.LastAppliedConfigAnnotation].(string); ok {
+		var obj map[string]interface{}
+		err := json.Unmarshal([]byte(applied), &obj)
+		if err != nil {
+			delete(annotations, corev1.LastAppliedConfigAnnotation)
+			return true
+		}
+
+		if redactObject(obj) {
+			data, err := json.Marshal(obj)
+			if err != nil {
+				delete(annotations, corev1.LastAppliedConfigAnnotation)
+				return true
+			}
+			annotations[corev1.LastAppliedConfigAnnotation] = string(data)
+			redacted = true
+		}
+	}
+
+	return redacted
+}
+
+func redactSecret(obj map[string]interface{}) bool {
+	if data, ok := obj["data"].(map[string]interface{}); ok {
+		for k := range data {
+			data[k] = base64.StdEncoding.EncodeToString([]byte(redactedValue))
+		}
+	}
+	if data, ok := obj["stringData"].(map[string]interface{}); ok {
+		for k := range data {
+			data[k] = redactedValue
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,190 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+
+	"github.com/giantswarm/microerror"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Difference describes a single field of a replayed response which differs
+// from the recorded one.
+type Difference struct {
+	Path     string
+	UID      string
+	Field    string
+	Recorded string
+	Replayed string
+}
+
+// Read reads entries from a JSONL recording.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var e Entry
+		err := json.Unmarshal(line, &e)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return entries, nil
+}
+
+// Replay feeds the recorded requests through the handler and compares the
+// responses with the recorded ones. The handler is expected to route the
+// requests by their path, like the webhooks server does. Differences are
+// printed to the output, and a mismatchError is returned if there are any.
+func Replay(handler http.Handler, entries []Entry, output io.Writer) ([]Difference, error) {
+	var differences []Difference
+
+	for _, e := range entries {
+		replayed, err := serve(handler, e)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		diffs, err := compare(e, replayed)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, d := range diffs {
+			_, err = fmt.Fprintf(output, "%s %s: %s differs\n  recorded: %s\n  replayed: %s\n", d.Path, d.UID, d.Field, d.Recorded, d.Replayed)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		differences = append(differences, diffs...)
+	}
+
+	_, err := fmt.Fprintf(output, "%d requests replayed, %d differences found\n", len(entries), len(differences))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(differences) > 0 {
+		return differences, microerror.Maskf(mismatchError, "%d differences found", len(differences))
+	}
+
+	return differences, nil
+}
+
+func serve(handler http.Handler, e Entry) (*admissionv1.AdmissionResponse, error) {
+	body, err := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "AdmissionReview",
+			APIVersion: "admission.k8s.io/v1",
+		},
+		Request: e.Request,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, e.Path, bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		return nil, microerror.Maskf(mismatchError, "replaying %s %s returned %d HTTP response", e.Path, e.Request.UID, recorder.Code)
+	}
+
+	var review admissionv1.AdmissionReview
+	err = json.Unmarshal(recorder.Body.Bytes(), &review)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return review.Response, nil
+}
+
+func compare(e Entry, replayed *admissionv1.AdmissionResponse) ([]Difference, error) {
+	var differences []Difference
+
+	add := func(field string, recorded, replayed interface{}) {
+		differences = append(differences, Difference{
+			Path:     e.Path,
+			UID:      string(e.Request.UID),
+			Field:    field,
+			Recorded: fmt.Sprint(recorded),
+			Replayed: fmt.Sprint(replayed),
+		})
+	}
+
+	if e.Response.Allowed != replayed.Allowed {
+		add("allowed", e.Response.Allowed, replayed.Allowed)
+	}
+
+	if message(e.Response) != message(replayed) {
+		add("message", message(e.Response), message(replayed))
+	}
+
+	equal, err := equalPatches(e.Response.Patch, replayed.Patch)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if !equal {
+		add("patch", string(e.Response.Patch), string(replayed.Patch))
+	}
+
+	return differences, nil
+}
+
+func equalPatches(a, b []byte) (bool, error) {
+	var x, y []interface{}
+
+	if len(a) > 0 {
+		err := json.Unmarshal(a, &x)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+	}
+	if len(b) > 0 {
+		err := json.Unmarshal(b, &y)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+	}
+
+	// Empty and missing patches are equal.
+	if len(x) == 0 && len(y) == 0 {
+		return true, nil
+	}
+
+	return reflect.DeepEqual(x, y), nil
+}
+
+func message(response *admissionv1.AdmissionResponse) string {
+	if response.Result == nil {
+		return ""
+	}
+
+	return response.Result.Message
+}
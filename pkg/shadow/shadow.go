@@ -0,0 +1,191 @@
+// Package shadow evaluates requests against a candidate security
+// configuration side by side with the live one, without affecting the
+// responses, so that the candidate can be promoted with confidence once it
+// agrees with the live configuration or only disagrees as intended.
+package shadow
+
+import (
+	"context"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/app-admission-controller/v2/pkg/app"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/metrics"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/webhook"
+)
+
+const (
+	decisionAllowed  = "allowed"
+	decisionRejected = "rejected"
+	decisionWarned   = "warned"
+
+	resultAgreed    = "agreed"
+	resultDisagreed = "disagreed"
+	resultSkipped   = "skipped"
+)
+
+type Config struct {
+	Logger micrologger.Logger
+
+	// Live decides the requests, Candidate is only evaluated.
+	Live      *app.Validator
+	Candidate *app.Validator
+
+	// MaxConcurrent bounds the concurrent evaluations of the candidate,
+	// further requests are skipped. Defaults to 10.
+	MaxConcurrent int
+}
+
+// Validator responds to requests with the decisions of the live validator,
+// and evaluates them against the candidate validator in the background.
+type Validator struct {
+	logger micrologger.Logger
+
+	live      *app.Validator
+	candidate *app.Validator
+
+	slots chan struct{}
+}
+
+// decision is the outcome of validating a request.
+type decision struct {
+	name   string
+	reason string
+}
+
+func New(config Config) (*Validator, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Live == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Live must not be empty", config)
+	}
+	if config.Candidate == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Candidate must not be empty", config)
+	}
+
+	if config.MaxConcurrent == 0 {
+		config.MaxConcurrent = 10
+	}
+
+	v := &Validator{
+		logger: config.Logger,
+
+		live:      config.Live,
+		candidate: config.Candidate,
+
+		slots: make(chan struct{}, config.MaxConcurrent),
+	}
+
+	return v, nil
+}
+
+// Load reads the candidate configuration from the YAML file.
+func Load(file string) (Candidate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Candidate{}, microerror.Mask(err)
+	}
+
+	var candidate Candidate
+	err = yaml.UnmarshalStrict(data, &candidate)
+	if err != nil {
+		return Candidate{}, microerror.Maskf(invalidConfigError, "%s: %s", file, err)
+	}
+
+	return candidate, nil
+}
+
+func (v *Validator) Debugf(ctx context.Context, format string, params ...interface{}) {
+	v.live.Debugf(ctx, format, params...)
+}
+
+func (v *Validator) Errorf(ctx context.Context, err error, format string, params ...interface{}) {
+	v.live.Errorf(ctx, err, format, params...)
+}
+
+func (v *Validator) Resource() string {
+	return v.live.Resource()
+}
+
+func (v *Validator) Webhook() webhook.Registration {
+	return v.live.Webhook()
+}
+
+func (v *Validator) Validate(request *admissionv1.AdmissionRequest) (bool, error) {
+	allowed, _, err := v.ValidateWithWarnings(request)
+	return allowed, err
+}
+
+func (v *Validator) ValidateWithWarnings(request *admissionv1.AdmissionRequest) (bool, []string, error) {
+	allowed, warnings, _, err := v.ValidateWithAudit(request)
+	return allowed, warnings, err
+}
+
+// ValidateWithAudit returns the decision of the live validator. The
+// candidate validator evaluates the request afterwards in the background,
+// unless too many evaluations are running already.
+func (v *Validator) ValidateWithAudit(request *admissionv1.AdmissionRequest) (bool, []string, map[string]string, error) {
+	allowed, warnings, auditAnnotations, err := v.live.ValidateWithAudit(request)
+	live := newDecision(allowed, warnings, err)
+
+	select {
+	case v.slots <- struct{}{}:
+		go func() {
+			defer func() { <-v.slots }()
+			v.shadow(context.Background(), request, live)
+		}()
+	default:
+		metrics.ShadowEvaluations.WithLabelValues(resultSkipped).Inc()
+	}
+
+	return allowed, warnings, auditAnnotations, err
+}
+
+// shadow evaluates the request against the candidate validator, and
+// records whether it agrees with the live decision. It returns false on
+// disagreements.
+func (v *Validator) shadow(ctx context.Context, request *admissionv1.AdmissionRequest, live decision) bool {
+	allowed, warnings, err := v.candidate.ValidateWithWarnings(request)
+	candidate := newDecision(allowed, warnings, err)
+
+	if candidate.name == live.name {
+		metrics.ShadowEvaluations.WithLabelValues(resultAgreed).Inc()
+		return true
+	}
+
+	metrics.ShadowEvaluations.WithLabelValues(resultDisagreed).Inc()
+	metrics.ShadowDisagreements.WithLabelValues(request.Namespace, live.name, candidate.name).Inc()
+
+	v.logger.LogCtx(ctx,
+		"level", "info",
+		"message", "candidate configuration disagrees with the live one",
+		"app", request.Name,
+		"namespace", request.Namespace,
+		"operation", string(request.Operation),
+		"user", request.UserInfo.Username,
+		"live", live.name,
+		"liveReason", live.reason,
+		"candidate", candidate.name,
+		"candidateReason", candidate.reason,
+	)
+
+	return false
+}
+
+func newDecision(allowed bool, warnings []string, err error) decision {
+	switch {
+	case err != nil:
+		return decision{name: decisionRejected, reason: err.Error()}
+	case !allowed:
+		return decision{name: decisionRejected}
+	case len(warnings) > 0:
+		return decision{name: decisionWarned, reason: warnings[0]}
+	default:
+		return decision{name: decisionAllowed}
+	}
+}
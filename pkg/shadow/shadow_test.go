@@ -0,0 +1,146 @@
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v8/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	admissionv1 "k8s.io/api/admission/v1"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgofake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+
+	"github.com/giantswarm/app-admission-controller/v2/internal/recorder"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/app"
+
+	secins "github.com/giantswarm/app-admission-controller/v2/internal/security/inspector"
+)
+
+func Test_Validator_shadow(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		CtrlClient: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithRuntimeObjects(&v1alpha1.Catalog{ObjectMeta: metav1.ObjectMeta{Name: "giantswarm", Namespace: "default"}}).
+			WithIndex(&v1alpha1.App{}, "metadata.name", func(obj client.Object) []string {
+				return []string{obj.GetName()}
+			}).
+			Build(),
+		K8sClient: clientgofake.NewClientset(
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "vault-token", Namespace: "giantswarm"}},
+		),
+	})
+
+	newValidator := func(namespaceBlacklist []string) *app.Validator {
+		ins, err := secins.New(secins.Config{
+			Logger:             microloggertest.New(),
+			NamespaceBlacklist: namespaceBlacklist,
+		})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		v, err := app.NewValidator(app.ValidatorConfig{
+			Event:     recorder.Discard{},
+			K8sClient: k8sClient,
+			Logger:    microloggertest.New(),
+			Provider:  "aws",
+			Inspector: ins,
+		})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		return v
+	}
+
+	live := newValidator(nil)
+
+	v, err := New(Config{
+		Logger: microloggertest.New(),
+
+		Live:      live,
+		Candidate: newValidator([]string{"giantswarm"}),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	tests := []struct {
+		name            string
+		secretNamespace string
+		expectedAgreed  bool
+	}{
+		{
+			name:            "case 0: both allow",
+			secretNamespace: "demo0",
+			expectedAgreed:  true,
+		},
+		{
+			name:            "case 1: candidate rejects blacklisted reference",
+			secretNamespace: "giantswarm",
+			expectedAgreed:  false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cr := v1alpha1.App{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hello-world",
+					Namespace: "demo0",
+					Labels: map[string]string{
+						"app-operator.giantswarm.io/version": "0.0.0",
+					},
+				},
+				Spec: v1alpha1.AppSpec{
+					Catalog:   "giantswarm",
+					Name:      "hello-world",
+					Namespace: "demo0",
+					KubeConfig: v1alpha1.AppSpecKubeConfig{
+						InCluster: true,
+					},
+					UserConfig: v1alpha1.AppSpecUserConfig{
+						Secret: v1alpha1.AppSpecUserConfigSecret{
+							Name:      "vault-token",
+							Namespace: tc.secretNamespace,
+						},
+					},
+					Version: "0.3.0",
+				},
+			}
+
+			raw, err := json.Marshal(cr)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			request := &admissionv1.AdmissionRequest{
+				Name:      cr.Name,
+				Namespace: cr.Namespace,
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+				UserInfo:  authv1.UserInfo{Username: "jane"},
+			}
+
+			allowed, _, err := live.ValidateWithWarnings(request)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			agreed := v.shadow(context.Background(), request, newDecision(allowed, nil, err))
+			if agreed != tc.expectedAgreed {
+				t.Fatalf("agreed == %t, want %t", agreed, tc.expectedAgreed)
+			}
+		})
+	}
+}
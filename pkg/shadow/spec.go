@@ -0,0 +1,32 @@
+package shadow
+
+import (
+	"github.com/giantswarm/app-admission-controller/v2/internal/expression"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/enforcement"
+	"github.com/giantswarm/app-admission-controller/v2/internal/security/policy"
+	"github.com/giantswarm/app-admission-controller/v2/pkg/admissionpolicy"
+)
+
+// Candidate is a security configuration evaluated side by side with the
+// live one. It replaces the live configuration, rather than adding to it.
+type Candidate struct {
+	// Whitelist and Blacklist hold matcher rules, like the --whitelist-*
+	// and --blacklist-* flags.
+	Whitelist admissionpolicy.Whitelist `json:"whitelist,omitempty"`
+	Blacklist admissionpolicy.Blacklist `json:"blacklist,omitempty"`
+
+	PrivilegedFields PrivilegedFields `json:"privilegedFields,omitempty"`
+
+	Policies []policy.Policy   `json:"policies,omitempty"`
+	Rules    []expression.Rule `json:"rules,omitempty"`
+
+	// Modes maps rule IDs to their enforcement modes, see the enforcement
+	// package.
+	Modes map[string]enforcement.Mode `json:"modes,omitempty"`
+}
+
+type PrivilegedFields struct {
+	Restricted bool     `json:"restricted,omitempty"`
+	Groups     []string `json:"groups,omitempty"`
+	Users      []string `json:"users,omitempty"`
+}
@@ -0,0 +1,62 @@
+package validator
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// causesError carries the field errors of a rejected request, which are
+// returned as causes in the admission response.
+type causesError struct {
+	causes []metav1.StatusCause
+	err    error
+}
+
+func (e *causesError) Error() string {
+	return e.err.Error()
+}
+
+func (e *causesError) Unwrap() error {
+	return e.err
+}
+
+// WithCauses attaches the field errors to the error, so that they are
+// returned as causes in the admission response. It returns nil for nil
+// errors.
+func WithCauses(err error, errs field.ErrorList) error {
+	if err == nil {
+		return nil
+	}
+
+	causes := make([]metav1.StatusCause, 0, len(errs))
+	for _, e := range errs {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseType(e.Type),
+			Message: e.ErrorBody(),
+			Field:   e.Field,
+		})
+	}
+
+	return &causesError{causes: causes, err: err}
+}
+
+// Causes returns the causes attached to the error, and to all errors joined
+// in it.
+func Causes(err error) []metav1.StatusCause {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *causesError:
+		return e.causes
+	case interface{ Unwrap() []error }:
+		var causes []metav1.StatusCause
+		for _, j := range e.Unwrap() {
+			causes = append(causes, Causes(j)...)
+		}
+		return causes
+	case interface{ Unwrap() error }:
+		return Causes(e.Unwrap())
+	default:
+		return nil
+	}
+}
@@ -24,6 +24,21 @@ type Validator interface {
 	Validate(review *admissionv1.AdmissionRequest) (bool, error)
 }
 
+// WarningValidator is a Validator also returning admission warnings of
+// admitted requests.
+type WarningValidator interface {
+	Validator
+	ValidateWithWarnings(review *admissionv1.AdmissionRequest) (bool, []string, error)
+}
+
+// AuditingValidator is a Validator also returning admission warnings and
+// audit annotations of admitted requests. The API server adds the audit
+// annotations to the audit events of the requests.
+type AuditingValidator interface {
+	Validator
+	ValidateWithAudit(review *admissionv1.AdmissionRequest) (bool, []string, map[string]string, error)
+}
+
 var (
 	scheme       = runtime.NewScheme()
 	codecs       = serializer.NewCodecFactory(scheme)
@@ -61,7 +76,16 @@ func Handler(validator Validator) http.HandlerFunc {
 			return
 		}
 
-		allowed, err := validator.Validate(review.Request)
+		var allowed bool
+		var warnings []string
+		var auditAnnotations map[string]string
+		if a, ok := validator.(AuditingValidator); ok {
+			allowed, warnings, auditAnnotations, err = a.ValidateWithAudit(review.Request)
+		} else if w, ok := validator.(WarningValidator); ok {
+			allowed, warnings, err = w.ValidateWithWarnings(review.Request)
+		} else {
+			allowed, err = validator.Validate(review.Request)
+		}
 		if err != nil {
 			writeResponse(ctx, validator, writer, errorResponse(review.Request.UID, microerror.Mask(err)))
 			metrics.RejectedRequests.WithLabelValues("validating", validator.Resource()).Inc()
@@ -71,8 +95,10 @@ func Handler(validator Validator) http.HandlerFunc {
 		metrics.SuccessfulRequests.WithLabelValues("validating", validator.Resource()).Inc()
 
 		writeResponse(ctx, validator, writer, &admissionv1.AdmissionResponse{
-			Allowed: allowed,
-			UID:     review.Request.UID,
+			Allowed:          allowed,
+			UID:              review.Request.UID,
+			Warnings:         warnings,
+			AuditAnnotations: auditAnnotations,
 		})
 	}
 }
@@ -97,11 +123,20 @@ func writeResponse(ctx context.Context, validator Validator, writer http.Respons
 }
 
 func errorResponse(uid types.UID, err error) *admissionv1.AdmissionResponse {
-	return &admissionv1.AdmissionResponse{
+	response := &admissionv1.AdmissionResponse{
 		Allowed: false,
 		UID:     uid,
 		Result: &metav1.Status{
 			Message: err.Error(),
 		},
 	}
+
+	if causes := Causes(err); len(causes) > 0 {
+		response.Result.Reason = metav1.StatusReasonInvalid
+		response.Result.Details = &metav1.StatusDetails{
+			Causes: causes,
+		}
+	}
+
+	return response
 }
@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Type is the type of an admission webhook.
+type Type string
+
+const (
+	Mutating   Type = "mutating"
+	Validating Type = "validating"
+)
+
+// Registration describes the requests an admitter handles. Admitters declare
+// it, so that webhook configurations are generated from it instead of being
+// maintained next to the code.
+type Registration struct {
+	// Name is the first segment of the webhook name, e.g. apps.
+	Name string
+	// Path is the URL path the webhook is served at.
+	Path string
+	// Rules are the resources and operations sent to the webhook.
+	Rules []admissionregistrationv1.RuleWithOperations
+
+	FailurePolicy     admissionregistrationv1.FailurePolicyType
+	NamespaceSelector *metav1.LabelSelector
+	ObjectSelector    *metav1.LabelSelector
+	SideEffects       admissionregistrationv1.SideEffectClass
+	TimeoutSeconds    int32
+}
+
+// Webhook is an admission webhook served by the webhooks server.
+type Webhook struct {
+	Registration
+
+	Handler http.Handler
+	Type    Type
+}
+
+type Config struct {
+	// Name is the name of the webhook configurations.
+	Name string
+	// Annotations are set on the webhook configurations, e.g. to let
+	// cert-manager inject the CA bundle.
+	Annotations map[string]string
+
+	// ServiceName and ServiceNamespace locate the Service the API server
+	// reaches the webhooks server at. They are ignored when URL is set.
+	ServiceName      string
+	ServiceNamespace string
+	// URL is the base URL the API server reaches the webhooks server at,
+	// e.g. https://host.docker.internal:8443.
+	URL string
+
+	// CABundle is the PEM encoded CA bundle to verify the serving
+	// certificate with. It is left empty when the CA bundle is injected,
+	// e.g. by cert-manager.
+	CABundle []byte
+}
+
+// Configurations returns the mutating and validating webhook configurations
+// for the given webhooks.
+func Configurations(config Config, webhooks []Webhook) (*admissionregistrationv1.MutatingWebhookConfiguration, *admissionregistrationv1.ValidatingWebhookConfiguration, error) {
+	if config.Name == "" {
+		return nil, nil, microerror.Maskf(invalidConfigError, "%T.Name must not be empty", config)
+	}
+	if config.URL == "" && (config.ServiceName == "" || config.ServiceNamespace == "") {
+		return nil, nil, microerror.Maskf(invalidConfigError, "%T.URL or %T.ServiceName and %T.ServiceNamespace must not be empty", config, config, config)
+	}
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "MutatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        config.Name,
+			Annotations: config.Annotations,
+		},
+	}
+
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "ValidatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        config.Name,
+			Annotations: config.Annotations,
+		},
+	}
+
+	for _, w := range webhooks {
+		if w.Name == "" || w.Path == "" || len(w.Rules) == 0 {
+			return nil, nil, microerror.Maskf(invalidConfigError, "webhook %#q must have a name, path and rules", w.Path)
+		}
+
+		name := fmt.Sprintf("%s.%s.giantswarm.io", w.Name, config.Name)
+		clientConfig := newClientConfig(config, w.Path)
+
+		// Pointers are taken from copies, so that webhooks do not share
+		// fields.
+		failurePolicy := w.FailurePolicy
+		sideEffects := w.SideEffects
+		timeoutSeconds := w.TimeoutSeconds
+
+		switch w.Type {
+		case Mutating:
+			mutating.Webhooks = append(mutating.Webhooks, admissionregistrationv1.MutatingWebhook{
+				Name:                    name,
+				AdmissionReviewVersions: []string{"v1", "v1beta1"},
+				ClientConfig:            clientConfig,
+				FailurePolicy:           &failurePolicy,
+				NamespaceSelector:       w.NamespaceSelector,
+				ObjectSelector:          w.ObjectSelector,
+				Rules:                   w.Rules,
+				SideEffects:             &sideEffects,
+				TimeoutSeconds:          &timeoutSeconds,
+			})
+		case Validating:
+			validating.Webhooks = append(validating.Webhooks, admissionregistrationv1.ValidatingWebhook{
+				Name:                    name,
+				AdmissionReviewVersions: []string{"v1", "v1beta1"},
+				ClientConfig:            clientConfig,
+				FailurePolicy:           &failurePolicy,
+				NamespaceSelector:       w.NamespaceSelector,
+				ObjectSelector:          w.ObjectSelector,
+				Rules:                   w.Rules,
+				SideEffects:             &sideEffects,
+				TimeoutSeconds:          &timeoutSeconds,
+			})
+		default:
+			return nil, nil, microerror.Maskf(invalidConfigError, "webhook %#q has unknown type %#q", w.Path, w.Type)
+		}
+	}
+
+	return mutating, validating, nil
+}
+
+// Print writes the given objects to the output as a multi document YAML.
+func Print(output io.Writer, objs ...client.Object) error {
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		_, err = fmt.Fprintf(output, "---\n%s", data)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// Install creates the given objects, or updates them when they already
+// exist. CA bundles injected into existing webhook configurations are kept
+// for webhooks without a CA bundle of their own.
+func Install(ctx context.Context, ctrlClient client.Client, objs ...client.Object) error {
+	for _, obj := range objs {
+		current := obj.DeepCopyObject().(client.Object)
+		err := ctrlClient.Get(ctx, client.ObjectKeyFromObject(obj), current)
+		if apierrors.IsNotFound(err) {
+			err = ctrlClient.Create(ctx, obj)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		keepCABundles(current, obj)
+
+		obj.SetResourceVersion(current.GetResourceVersion())
+		err = ctrlClient.Update(ctx, obj)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// Uninstall deletes the given objects, ignoring the ones which do not exist.
+func Uninstall(ctx context.Context, ctrlClient client.Client, objs ...client.Object) error {
+	for _, obj := range objs {
+		err := ctrlClient.Delete(ctx, obj)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+func newClientConfig(config Config, path string) admissionregistrationv1.WebhookClientConfig {
+	clientConfig := admissionregistrationv1.WebhookClientConfig{
+		CABundle: config.CABundle,
+	}
+
+	if config.URL != "" {
+		url := strings.TrimSuffix(config.URL, "/") + path
+		clientConfig.URL = &url
+	} else {
+		clientConfig.Service = &admissionregistrationv1.ServiceReference{
+			Name:      config.ServiceName,
+			Namespace: config.ServiceNamespace,
+			Path:      &path,
+		}
+	}
+
+	return clientConfig
+}
+
+func keepCABundles(current, desired client.Object) {
+	caBundles := map[string][]byte{}
+
+	switch c := current.(type) {
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		for _, w := range c.Webhooks {
+			caBundles[w.Name] = w.ClientConfig.CABundle
+		}
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		for _, w := range c.Webhooks {
+			caBundles[w.Name] = w.ClientConfig.CABundle
+		}
+	}
+
+	switch d := desired.(type) {
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		for i, w := range d.Webhooks {
+			if len(w.ClientConfig.CABundle) == 0 {
+				d.Webhooks[i].ClientConfig.CABundle = caBundles[w.Name]
+			}
+		}
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		for i, w := range d.Webhooks {
+			if len(w.ClientConfig.CABundle) == 0 {
+				d.Webhooks[i].ClientConfig.CABundle = caBundles[w.Name]
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+)
+
+var testWebhooks = []Webhook{
+	{
+		Registration: Registration{
+			Name: "apps",
+			Path: "/mutate/app",
+			Rules: []admissionregistrationv1.RuleWithOperations{
+				{
+					Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+					Rule: admissionregistrationv1.Rule{
+						APIGroups:   []string{"application.giantswarm.io"},
+						APIVersions: []string{"v1alpha1"},
+						Resources:   []string{"apps"},
+					},
+				},
+			},
+			FailurePolicy:  admissionregistrationv1.Fail,
+			SideEffects:    admissionregistrationv1.SideEffectClassNoneOnDryRun,
+			TimeoutSeconds: 10,
+		},
+		Type: Mutating,
+	},
+}
+
+func Test_Configurations(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		expectedURL  string
+		expectedPath string
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "case 0: service",
+			config: Config{
+				Name:             "app-admission-controller",
+				ServiceName:      "app-admission-controller",
+				ServiceNamespace: "giantswarm",
+			},
+			expectedPath: "/mutate/app",
+		},
+		{
+			name: "case 1: URL",
+			config: Config{
+				Name: "app-admission-controller-dev",
+				URL:  "https://host.docker.internal:8443/",
+			},
+			expectedURL: "https://host.docker.internal:8443/mutate/app",
+		},
+		{
+			name: "case 2: neither service nor URL",
+			config: Config{
+				Name: "app-admission-controller",
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mutating, validating, err := Configurations(tc.config, testWebhooks)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			case err != nil:
+				return
+			}
+
+			if len(mutating.Webhooks) != 1 || len(validating.Webhooks) != 0 {
+				t.Fatalf("got %d mutating and %d validating webhooks, want 1 and 0", len(mutating.Webhooks), len(validating.Webhooks))
+			}
+
+			clientConfig := mutating.Webhooks[0].ClientConfig
+			if tc.expectedURL != "" && (clientConfig.URL == nil || *clientConfig.URL != tc.expectedURL) {
+				t.Fatalf("URL == %v, want %#q", clientConfig.URL, tc.expectedURL)
+			}
+			if tc.expectedPath != "" && (clientConfig.Service == nil || *clientConfig.Service.Path != tc.expectedPath) {
+				t.Fatalf("service == %v, want path %#q", clientConfig.Service, tc.expectedPath)
+			}
+		})
+	}
+}
+
+func Test_Install_KeepsCABundle(t *testing.T) {
+	ctx := context.Background()
+	caBundle := []byte("injected")
+
+	ctrlClient := fake.NewClientBuilder().
+		WithScheme(clientgoscheme.Scheme).
+		WithObjects(&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-admission-controller"},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{
+				{
+					Name:         "apps.app-admission-controller.giantswarm.io",
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: caBundle},
+				},
+			},
+		}).
+		Build()
+
+	mutating, _, err := Configurations(Config{
+		Name:             "app-admission-controller",
+		ServiceName:      "app-admission-controller",
+		ServiceNamespace: "giantswarm",
+	}, testWebhooks)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = Install(ctx, ctrlClient, mutating)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	current := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	err = ctrlClient.Get(ctx, client.ObjectKey{Name: "app-admission-controller"}, current)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if !bytes.Equal(current.Webhooks[0].ClientConfig.CABundle, caBundle) {
+		t.Fatalf("CA bundle == %q, want %q", current.Webhooks[0].ClientConfig.CABundle, caBundle)
+	}
+	if current.Webhooks[0].TimeoutSeconds == nil || *current.Webhooks[0].TimeoutSeconds != 10 {
+		t.Fatalf("webhook was not updated")
+	}
+}